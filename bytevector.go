@@ -0,0 +1,317 @@
+package goscheme
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Bytevector represents the sequence of bytes for binary data. Should only use with pointer.
+// Bytes is exported so the embedders can pass the binary data in and out without copying.
+type Bytevector struct {
+	Bytes []byte
+}
+
+// NewBytevector wraps b in a *Bytevector without copying.
+func NewBytevector(b []byte) *Bytevector {
+	return &Bytevector{Bytes: b}
+}
+
+// String returns the string representing the *Bytevector such as #u8(1 2 3).
+func (bv *Bytevector) String() string {
+	strSlices := make([]string, len(bv.Bytes))
+	for i, b := range bv.Bytes {
+		strSlices[i] = strconv.Itoa(int(b))
+	}
+	return "#u8(" + strings.Join(strSlices, " ") + ")"
+}
+
+// IsBytevector checks whether the expression represents Bytevector.
+func IsBytevector(exp Expression) bool {
+	switch exp.(type) {
+	case *Bytevector, bytevectorLiteral:
+		return true
+	default:
+		return false
+	}
+}
+
+// bytevectorLiteral is the parsed #u8( ... ) syntax. Like vectorLiteral, it evaluates to a new *Bytevector each time,
+// so modifying the value of the literal does not change the later evaluations.
+type bytevectorLiteral []byte
+
+// String returns the string representing the literal such as #u8(1 2 3).
+func (b bytevectorLiteral) String() string {
+	return NewBytevector(b).String()
+}
+
+// bytevector returns a new *Bytevector with the bytes of the literal.
+func (b bytevectorLiteral) bytevector() *Bytevector {
+	return NewBytevector(append([]byte{}, b...))
+}
+
+func expressionToBytevector(exp Expression) (*Bytevector, error) {
+	bv, ok := exp.(*Bytevector)
+	if !ok {
+		return nil, fmt.Errorf("%v is not a bytevector", valueToString(exp))
+	}
+	return bv, nil
+}
+
+// expressionToByte converts the exact integer between 0 and 255 to byte.
+func expressionToByte(exp Expression) (byte, error) {
+	n, ok := exp.(Integer)
+	if !ok || n < 0 || n > 255 {
+		return 0, fmt.Errorf("%v is not a byte", valueToString(exp))
+	}
+	return byte(n), nil
+}
+
+// byteOrderArg converts the endianness symbol big or little to binary.ByteOrder.
+func byteOrderArg(exp Expression) (binary.ByteOrder, error) {
+	switch exp {
+	case Symbol("big"):
+		return binary.BigEndian, nil
+	case Symbol("little"):
+		return binary.LittleEndian, nil
+	default:
+		return nil, fmt.Errorf("%v is not a valid endianness", valueToString(exp))
+	}
+}
+
+func isBytevectorFunc(args ...Expression) (Expression, error) {
+	return IsBytevector(args[0]), nil
+}
+
+func makeBytevectorFunc(args ...Expression) (Expression, error) {
+	k, ok := args[0].(Integer)
+	if !ok || k < 0 {
+		return UndefObj, fmt.Errorf("make-bytevector: %v is not a valid length", valueToString(args[0]))
+	}
+	var fill byte
+	if len(args) > 1 {
+		b, err := expressionToByte(args[1])
+		if err != nil {
+			return UndefObj, err
+		}
+		fill = b
+	}
+	bv := NewBytevector(make([]byte, k))
+	for i := range bv.Bytes {
+		bv.Bytes[i] = fill
+	}
+	return bv, nil
+}
+
+func bytevectorFunc(args ...Expression) (Expression, error) {
+	bytes := make([]byte, len(args))
+	for i, arg := range args {
+		b, err := expressionToByte(arg)
+		if err != nil {
+			return UndefObj, err
+		}
+		bytes[i] = b
+	}
+	return NewBytevector(bytes), nil
+}
+
+func bytevectorLengthFunc(args ...Expression) (Expression, error) {
+	bv, err := expressionToBytevector(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	return Integer(len(bv.Bytes)), nil
+}
+
+func bytevectorU8RefFunc(args ...Expression) (Expression, error) {
+	bv, err := expressionToBytevector(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	k, err := indexArg(args, 1, len(bv.Bytes))
+	if err != nil {
+		return UndefObj, fmt.Errorf("bytevector-u8-ref: %s", err)
+	}
+	return Integer(bv.Bytes[k]), nil
+}
+
+func bytevectorU8SetFunc(args ...Expression) (Expression, error) {
+	bv, err := expressionToBytevector(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	k, err := indexArg(args, 1, len(bv.Bytes))
+	if err != nil {
+		return UndefObj, fmt.Errorf("bytevector-u8-set!: %s", err)
+	}
+	b, err := expressionToByte(args[2])
+	if err != nil {
+		return UndefObj, err
+	}
+	bv.Bytes[k] = b
+	return UndefObj, nil
+}
+
+// subBytes returns the bytes of the bytevector argument in the range specified by the optional arguments.
+func subBytes(name string, args []Expression) ([]byte, error) {
+	bv, err := expressionToBytevector(args[0])
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := rangeArgs(args, 1, len(bv.Bytes))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	return bv.Bytes[start:end], nil
+}
+
+func bytevectorCopyFunc(args ...Expression) (Expression, error) {
+	bytes, err := subBytes("bytevector-copy", args)
+	if err != nil {
+		return UndefObj, err
+	}
+	return NewBytevector(append([]byte(nil), bytes...)), nil
+}
+
+func bytevectorCopyToFunc(args ...Expression) (Expression, error) {
+	to, err := expressionToBytevector(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	at, err := indexArg(args, 1, len(to.Bytes)+1)
+	if err != nil {
+		return UndefObj, fmt.Errorf("bytevector-copy!: %s", err)
+	}
+	bytes, err := subBytes("bytevector-copy!", args[2:])
+	if err != nil {
+		return UndefObj, err
+	}
+	if at+len(bytes) > len(to.Bytes) {
+		return UndefObj, fmt.Errorf("bytevector-copy!: not enough room in %v", valueToString(to))
+	}
+	copy(to.Bytes[at:], bytes)
+	return UndefObj, nil
+}
+
+func bytevectorAppendFunc(args ...Expression) (Expression, error) {
+	var bytes []byte
+	for _, arg := range args {
+		bv, err := expressionToBytevector(arg)
+		if err != nil {
+			return UndefObj, err
+		}
+		bytes = append(bytes, bv.Bytes...)
+	}
+	return NewBytevector(bytes), nil
+}
+
+func utf8ToStringFunc(args ...Expression) (Expression, error) {
+	bytes, err := subBytes("utf8->string", args)
+	if err != nil {
+		return UndefObj, err
+	}
+	if !utf8.Valid(bytes) {
+		return UndefObj, fmt.Errorf("utf8->string: %v is not valid utf-8", valueToString(args[0]))
+	}
+	return NewMutableString(string(bytes)), nil
+}
+
+func stringToUtf8Func(args ...Expression) (Expression, error) {
+	runes, err := substringRunes("string->utf8", args)
+	if err != nil {
+		return UndefObj, err
+	}
+	return NewBytevector([]byte(string(runes))), nil
+}
+
+func nativeEndiannessFunc(args ...Expression) (Expression, error) {
+	if binary.NativeEndian.Uint16([]byte{1, 0}) == 1 {
+		return intern("little"), nil
+	}
+	return intern("big"), nil
+}
+
+// bytevectorIntRange returns the bytes of the size bytes integer at the index argument of the bytevector argument.
+func bytevectorIntRange(name string, args []Expression, size int) ([]byte, error) {
+	bv, err := expressionToBytevector(args[0])
+	if err != nil {
+		return nil, err
+	}
+	k, err := indexArg(args, 1, len(bv.Bytes)-size+1)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	return bv.Bytes[k : k+size], nil
+}
+
+// bytevectorIntRef returns the builtin function reading the size bytes integer with the specified endianness.
+func bytevectorIntRef(name string, size int, signed bool) commonFunction {
+	return func(args ...Expression) (Expression, error) {
+		bytes, err := bytevectorIntRange(name, args, size)
+		if err != nil {
+			return UndefObj, err
+		}
+		order, err := byteOrderArg(args[2])
+		if err != nil {
+			return UndefObj, err
+		}
+		var u uint64
+		switch size {
+		case 2:
+			u = uint64(order.Uint16(bytes))
+		case 4:
+			u = uint64(order.Uint32(bytes))
+		default:
+			u = order.Uint64(bytes)
+		}
+		bits := uint(size * 8)
+		if signed {
+			// sign extend by shifting the sign bit to the highest bit of int64
+			return Integer(int64(u<<(64-bits)) >> (64 - bits)), nil
+		}
+		return normalizeBigInt(new(big.Int).SetUint64(u)), nil
+	}
+}
+
+// bytevectorIntSet returns the builtin function writing the size bytes integer with the specified endianness.
+func bytevectorIntSet(name string, size int, signed bool) commonFunction {
+	return func(args ...Expression) (Expression, error) {
+		bytes, err := bytevectorIntRange(name, args, size)
+		if err != nil {
+			return UndefObj, err
+		}
+		order, err := byteOrderArg(args[3])
+		if err != nil {
+			return UndefObj, err
+		}
+		n, ok := args[2].(Number)
+		if !ok || toBigInt(n) == nil {
+			return UndefObj, fmt.Errorf("%s: %v is not an exact integer", name, valueToString(args[2]))
+		}
+		bits := uint(size * 8)
+		v := new(big.Int).Set(toBigInt(n))
+		lo, hi := big.NewInt(0), new(big.Int).Lsh(big.NewInt(1), bits)
+		if signed {
+			lo.Neg(new(big.Int).Lsh(big.NewInt(1), bits-1))
+			hi.Add(hi, lo)
+		}
+		if v.Cmp(lo) < 0 || v.Cmp(hi) >= 0 {
+			return UndefObj, fmt.Errorf("%s: %v is out of range", name, valueToString(args[2]))
+		}
+		if v.Sign() < 0 {
+			v.Add(v, new(big.Int).Lsh(big.NewInt(1), bits))
+		}
+		switch size {
+		case 2:
+			order.PutUint16(bytes, uint16(v.Uint64()))
+		case 4:
+			order.PutUint32(bytes, uint32(v.Uint64()))
+		default:
+			order.PutUint64(bytes, v.Uint64())
+		}
+		return UndefObj, nil
+	}
+}
@@ -0,0 +1,80 @@
+package goscheme
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBytevector_String(t *testing.T) {
+	assert.Equal(t, "#u8()", NewBytevector(nil).String())
+	assert.Equal(t, "#u8(1 255 0)", NewBytevector([]byte{1, 255, 0}).String())
+}
+
+func TestBytevectorFunctions(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"#u8(1 2 255)", "#u8(1 2 255)"},
+		{"'#u8(1)", "#u8(1)"},
+		{"(bytevector? #u8())", "#t"},
+		{"(bytevector? #(1))", "#f"},
+		{"(make-bytevector 2 7)", "#u8(7 7)"},
+		{"(bytevector 1 2)", "#u8(1 2)"},
+		{"(bytevector-length #u8(1 2 3))", "3"},
+		{"(bytevector-u8-ref #u8(1 2 3) 2)", "3"},
+		{"(let ((bv (make-bytevector 2 0))) (bytevector-u8-set! bv 1 255) bv)", "#u8(0 255)"},
+		{"(bytevector-copy #u8(1 2 3) 1)", "#u8(2 3)"},
+		{"(let ((bv (bytevector 1 2 3 4))) (bytevector-copy! bv 1 #u8(9 8 7) 1) bv)", "#u8(1 8 7 4)"},
+		{"(bytevector-append #u8(1) #u8() #u8(2 3))", "#u8(1 2 3)"},
+		// the literals evaluate to new bytevectors each time like the vector literals
+		{"(define (f) #u8(1 2)) (bytevector-u8-set! (f) 0 9) (f)", "#u8(1 2)"},
+		{"(define (g) '#u8(1 2)) (bytevector-u8-set! (g) 0 9) (g)", "#u8(1 2)"},
+		{"(equal? (f) (g))", "#t"},
+		{`(utf8->string #u8(206 187 97))`, `"λa"`},
+		{`(utf8->string #u8(97 98 99) 1 2)`, `"b"`},
+		{`(string->utf8 "λa")`, "#u8(206 187 97)"},
+		{`(string->utf8 "λab" 1)`, "#u8(97 98)"},
+		{"(bytevector-u16-ref #u8(1 2) 0 'big)", "258"},
+		{"(bytevector-u16-ref #u8(1 2) 0 'little)", "513"},
+		{"(bytevector-s16-ref #u8(255 254) 0 'big)", "-2"},
+		{"(bytevector-u32-ref #u8(0 0 0 1 0) 1 'little)", "65536"},
+		{"(bytevector-s32-ref #u8(255 255 255 255) 0 'little)", "-1"},
+		{"(bytevector-u64-ref #u8(255 255 255 255 255 255 255 255) 0 'big)", "18446744073709551615"},
+		{"(bytevector-s64-ref #u8(255 255 255 255 255 255 255 254) 0 'big)", "-2"},
+		{"(let ((bv (make-bytevector 4 0))) (bytevector-u16-set! bv 1 258 'little) bv)", "#u8(0 2 1 0)"},
+		{"(let ((bv (make-bytevector 2 0))) (bytevector-s16-set! bv 0 -2 'big) bv)", "#u8(255 254)"},
+		{"(let ((bv (make-bytevector 4 0))) (bytevector-s32-set! bv 0 -2147483648 'big) bv)", "#u8(128 0 0 0)"},
+		{"(let ((bv (make-bytevector 8 0))) (bytevector-u64-set! bv 0 18446744073709551615 'little) bv)",
+			"#u8(255 255 255 255 255 255 255 255)"},
+		{"(let ((bv (make-bytevector 8 0))) (bytevector-s64-set! bv 0 -1 'little) (bytevector-s64-ref bv 0 'big))", "-1"},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+
+	for _, input := range []string{"#u8(256)", "#u8(a)", "#u8(1.0)"} {
+		tokens := Tokenize(input)
+		_, err := Parse(&tokens)
+		assert.NotNil(t, err, input)
+	}
+
+	for _, input := range []string{"(bytevector 1 -1)", "(bytevector-u8-ref #u8(1) 1)",
+		"(bytevector-u16-ref #u8(1) 0 'big)", "(bytevector-u16-ref #u8(1 2) 0 'middle)",
+		"(bytevector-u16-set! (make-bytevector 2) 0 65536 'big)", "(bytevector-s16-set! (make-bytevector 2) 0 32768 'big)",
+		"(bytevector-u32-set! (make-bytevector 4) 0 -1 'big)", "(utf8->string #u8(255))",
+		"(bytevector-copy! (make-bytevector 1) 0 #u8(1 2))"} {
+		_, err := EvalAll(strToToken(input), env)
+		assert.NotNil(t, err, input)
+	}
+
+	// the bytes are shared with go without copying
+	bytes := []byte{1, 2}
+	env.Set("bv", NewBytevector(bytes))
+	_, err := EvalAll(strToToken("(bytevector-u8-set! bv 0 9)"), env)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{9, 2}, bytes)
+}
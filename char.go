@@ -0,0 +1,183 @@
+package goscheme
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Char represents a unicode character in scheme.
+type Char rune
+
+// charNames maps the character names in literals such as #\space to the characters.
+var charNames = map[string]Char{
+	"alarm":     '\a',
+	"backspace": '\b',
+	"delete":    0x7f,
+	"escape":    0x1b,
+	"newline":   '\n',
+	"null":      0,
+	"return":    '\r',
+	"space":     ' ',
+	"tab":       '\t',
+}
+
+// String returns the literal representing the Char such as #\a, #\space and #\x3bb.
+func (c Char) String() string {
+	for name, ch := range charNames {
+		if ch == c {
+			return `#\` + name
+		}
+	}
+	if !unicode.IsPrint(rune(c)) {
+		return `#\x` + strconv.FormatInt(int64(c), 16)
+	}
+	return `#\` + string(c)
+}
+
+// IsChar checks whether the expression represents Char.
+func IsChar(exp Expression) bool {
+	switch v := exp.(type) {
+	case string:
+		_, ok := parseChar(v)
+		return ok
+	case Char:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseChar reads the character literal such as #\a, #\space and #\x41.
+func parseChar(s string) (Char, bool) {
+	if !strings.HasPrefix(s, `#\`) {
+		return 0, false
+	}
+	s = s[2:]
+	runes := []rune(s)
+	switch {
+	case len(runes) == 1:
+		return Char(runes[0]), true
+	case len(runes) == 0:
+		return 0, false
+	}
+	if c, ok := charNames[s]; ok {
+		return c, true
+	}
+	if runes[0] == 'x' || runes[0] == 'X' {
+		code, err := strconv.ParseUint(s[1:], 16, 32)
+		if err == nil && code <= unicode.MaxRune {
+			return Char(code), true
+		}
+	}
+	return 0, false
+}
+
+func expressionToChar(exp Expression) (Char, error) {
+	switch v := exp.(type) {
+	case Char:
+		return v, nil
+	case string:
+		if c, ok := parseChar(v); ok {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("%v is not a character", valueToString(exp))
+}
+
+// charArgs converts the arguments to characters, folding their cases if foldCase is true.
+func charArgs(args []Expression, foldCase bool) ([]Char, error) {
+	ret := make([]Char, 0, len(args))
+	for _, arg := range args {
+		c, err := expressionToChar(arg)
+		if err != nil {
+			return nil, err
+		}
+		if foldCase {
+			c = Char(foldRune(rune(c)))
+		}
+		ret = append(ret, c)
+	}
+	return ret, nil
+}
+
+// compareChars returns the builtin function comparing each adjacent pair of the character arguments.
+func compareChars(foldCase bool, test func(a, b Char) bool) commonFunction {
+	return func(args ...Expression) (Expression, error) {
+		chars, err := charArgs(args, foldCase)
+		if err != nil {
+			return UndefObj, err
+		}
+		for i := 0; i < len(chars)-1; i++ {
+			if !test(chars[i], chars[i+1]) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+// charPredicate returns the builtin function checking the character argument with the test function.
+func charPredicate(test func(r rune) bool) commonFunction {
+	return func(args ...Expression) (Expression, error) {
+		c, err := expressionToChar(args[0])
+		if err != nil {
+			return UndefObj, err
+		}
+		return test(rune(c)), nil
+	}
+}
+
+// charConverter returns the builtin function mapping the character argument with the convert function.
+func charConverter(convert func(r rune) rune) commonFunction {
+	return func(args ...Expression) (Expression, error) {
+		c, err := expressionToChar(args[0])
+		if err != nil {
+			return UndefObj, err
+		}
+		return Char(convert(rune(c))), nil
+	}
+}
+
+// foldRune returns the case folded rune of r.
+func foldRune(r rune) rune {
+	return unicode.ToLower(unicode.ToUpper(r))
+}
+
+func isCharFunc(args ...Expression) (Expression, error) {
+	_, ok := args[0].(Char)
+	return ok, nil
+}
+
+func charToIntegerFunc(args ...Expression) (Expression, error) {
+	c, err := expressionToChar(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	return Integer(c), nil
+}
+
+func integerToCharFunc(args ...Expression) (Expression, error) {
+	code, ok := args[0].(Integer)
+	if !ok || code < 0 || code > unicode.MaxRune || (code >= 0xd800 && code <= 0xdfff) {
+		return UndefObj, fmt.Errorf("integer->char: %v is not a valid unicode scalar value", valueToString(args[0]))
+	}
+	return Char(code), nil
+}
+
+func digitValueFunc(args ...Expression) (Expression, error) {
+	c, err := expressionToChar(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	if !unicode.IsDigit(rune(c)) {
+		return false, nil
+	}
+	// decimal digits of unicode are arranged in continuous blocks of ten characters starting from zero
+	zero := rune(c)
+	for unicode.IsDigit(zero - 1) {
+		zero--
+	}
+	return Integer((rune(c) - zero) % 10), nil
+}
@@ -0,0 +1,100 @@
+package goscheme
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseChar(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected Char
+		ok       bool
+	}{
+		{`#\a`, 'a', true},
+		{`#\A`, 'A', true},
+		{`#\(`, '(', true},
+		{`#\ `, ' ', true},
+		{`#\space`, ' ', true},
+		{`#\newline`, '\n', true},
+		{`#\tab`, '\t', true},
+		{`#\x`, 'x', true},
+		{`#\x41`, 'A', true},
+		{`#\x3bb`, 'λ', true},
+		{`#\λ`, 'λ', true},
+		{`#\`, 0, false},
+		{`#\abc`, 0, false},
+		{`a`, 0, false},
+	}
+	for _, c := range testCases {
+		ret, ok := parseChar(c.input)
+		assert.Equal(t, c.ok, ok, c.input)
+		assert.Equal(t, c.expected, ret, c.input)
+	}
+}
+
+func TestChar_String(t *testing.T) {
+	testCases := []struct {
+		input    Char
+		expected string
+	}{
+		{'a', `#\a`},
+		{' ', `#\space`},
+		{'\n', `#\newline`},
+		{'λ', `#\λ`},
+		{0x7f, `#\delete`},
+		{0x1, `#\x1`},
+	}
+	for _, c := range testCases {
+		assert.Equal(t, c.expected, c.input.String())
+	}
+}
+
+func TestCharFunctions(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected Expression
+	}{
+		{`#\a`, Char('a')},
+		{`'#\a`, Char('a')},
+		{`'(#\a #\b)`, &Pair{Char('a'), &Pair{Char('b'), NilObj}}},
+		{`(char? #\a)`, true},
+		{`(char? "a")`, false},
+		{`(char->integer #\A)`, Integer(65)},
+		{`(char->integer #\x3bb)`, Integer(955)},
+		{`(integer->char 955)`, Char('λ')},
+		{`(char-upcase #\a)`, Char('A')},
+		{`(char-downcase #\A)`, Char('a')},
+		{`(char-foldcase #\A)`, Char('a')},
+		{`(char-alphabetic? #\a)`, true},
+		{`(char-numeric? #\1)`, true},
+		{`(char-whitespace? #\tab)`, true},
+		{`(char-upper-case? #\a)`, false},
+		{`(char-lower-case? #\a)`, true},
+		{`(digit-value #\7)`, Integer(7)},
+		{`(digit-value #\x0664)`, Integer(4)},
+		{`(digit-value #\a)`, false},
+		{`(char=? #\a #\a #\a)`, true},
+		{`(char<? #\a #\b #\c)`, true},
+		{`(char<? #\a #\c #\b)`, false},
+		{`(char>=? #\b #\b #\a)`, true},
+		{`(char-ci=? #\a #\A)`, true},
+		{`(char-ci<? #\a #\B)`, true},
+		{`(string-ref "abc" 1)`, Char('b')},
+		{`(string-ref "λμν" 2)`, Char('ν')},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, ret, c.input)
+	}
+	for _, input := range []string{`(string-ref "abc" 3)`, `(integer->char -1)`, `(char-upcase "a")`} {
+		_, err := EvalAll(strToToken(input), env)
+		assert.NotNil(t, err, input)
+	}
+	// the unknown character name is rejected by the reader
+	tokens := Tokenize(`#\abc`)
+	_, err := Parse(&tokens)
+	assert.NotNil(t, err)
+}
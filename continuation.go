@@ -0,0 +1,414 @@
+package goscheme
+
+import (
+	"errors"
+	"fmt"
+)
+
+// continuation is the rest of the computation waiting for a value, a linked stack of frames. The frames never change
+// after pushed, so a captured continuation can be resumed any number of times.
+type continuation struct {
+	frame frame
+	next  *continuation
+}
+
+// frame receives the value of the evaluated expression and sets the next state of the machine.
+type frame func(m *machine, value Expression) error
+
+// machine evaluates the expression with the explicit continuation instead of the go stack, so non-tail calls do not
+// grow the go stack and the continuation can be captured by call/cc.
+type machine struct {
+	// exp is the expression to evaluate in env, unless the machine is returning value to k. env is also the
+	// environment of the procedure call while the procedure is applied.
+	exp       Expression
+	env       *Env
+	value     Expression
+	returning bool
+	k         *continuation
+	// winders is the dynamic extents entered by dynamic-wind, the innermost first
+	winders *winder
+	// handlers is the exception handlers installed, the innermost first
+	handlers *exceptionHandler
+	// done is set when the machine stops, the continuations captured in it no longer unwind the go stack to it
+	done bool
+}
+
+// eval sets the expression to evaluate next with the current continuation, which makes the tail call.
+func (m *machine) eval(exp Expression, env *Env) {
+	m.exp, m.env, m.returning = exp, env, false
+}
+
+// evalThen evaluates the expression and passes the value to the frame.
+func (m *machine) evalThen(exp Expression, env *Env, f frame) {
+	m.push(f)
+	m.eval(exp, env)
+}
+
+func (m *machine) push(f frame) {
+	m.k = &continuation{frame: f, next: m.k}
+}
+
+// ret returns the value to the current continuation.
+func (m *machine) ret(value Expression) {
+	m.value, m.returning = value, true
+}
+
+// start sets the machine to call the procedure with the arguments when it runs.
+func (m *machine) start(procedure Expression, args []Expression) {
+	m.push(func(m *machine, _ Expression) error {
+		return m.apply(procedure, args)
+	})
+	m.ret(UndefObj)
+}
+
+// run steps the machine until the continuation is empty and returns the final value.
+func (m *machine) run() (Expression, error) {
+	defer func() { m.done = true }()
+	for {
+		var err error
+		if m.returning {
+			if m.k == nil {
+				return m.value, nil
+			}
+			f := m.k.frame
+			m.k = m.k.next
+			err = f(m, m.value)
+		} else {
+			err = m.step()
+		}
+		if err = m.recover(err); err != nil {
+			return UndefObj, err
+		}
+	}
+}
+
+// recover enters the continuation unwinding the go stack to the machine, and raises the other errors to the exception
+// handlers. The errors not handled leave the machine after the after thunks of the dynamic extents run.
+func (m *machine) recover(err error) error {
+	for err != nil {
+		escape, ok := err.(*continuationInvoked)
+		if ok && escape.target.run == m {
+			err = escape.target.enter(m, escape.value)
+			continue
+		}
+		if !ok && m.handlers != nil {
+			err = m.raise(condition(err), false)
+			continue
+		}
+		if m.winders == nil {
+			return err
+		}
+		pending := err
+		m.k = nil
+		err = m.rewind(nil, func(m *machine) error { return pending })
+	}
+	return nil
+}
+
+// step evaluates one level of the expression, the symbols are looked up, the lists are the applications and the other
+// datums evaluate to themselves.
+func (m *machine) step() error {
+	exp, env := m.exp, m.env
+	switch e := exp.(type) {
+	case Symbol, *identifier:
+		v, err := env.findSymbol(e)
+		if err != nil {
+			return err
+		}
+		m.ret(v)
+	case []Expression:
+		if len(e) == 0 {
+			m.ret(NilObj)
+			return nil
+		}
+		return m.stepApplication(e, env)
+	case vectorLiteral:
+		v, err := quoteVector(e)
+		if err != nil {
+			return err
+		}
+		m.ret(v)
+	case bytevectorLiteral:
+		m.ret(e.bytevector())
+	case nil:
+		m.ret(NilObj)
+	default:
+		m.ret(exp)
+	}
+	return nil
+}
+
+// stepApplication evaluates the syntax, macro use or procedure call.
+func (m *machine) stepApplication(ops []Expression, env *Env) error {
+	// syntax and macros are looked up in env, so they can be shadowed and scoped like variables
+	if IsSymbol(ops[0]) {
+		if head, err := env.findSymbol(ops[0]); err == nil {
+			switch h := head.(type) {
+			case *Syntax:
+				return h.apply(m, ops[1:], env)
+			case transformer:
+				expanded, err := h.Transform(ops, env)
+				if err != nil {
+					return err
+				}
+				m.eval(expanded, env)
+				return nil
+			}
+		}
+	}
+	m.evalThen(ops[0], env, func(m *machine, procedure Expression) error {
+		return m.evalArgs(keywordOperands(procedure, ops[1:]), env, func(m *machine, args []Expression) error {
+			// the control functions such as macroexpand work in the environment of the call
+			m.env = env
+			return m.apply(procedure, args)
+		})
+	})
+	return nil
+}
+
+// evalArgs evaluates the expressions from left to right and passes the values to then.
+func (m *machine) evalArgs(exps []Expression, env *Env, then func(m *machine, values []Expression) error) error {
+	var next func(m *machine, values []Expression) error
+	next = func(m *machine, values []Expression) error {
+		if len(values) == len(exps) {
+			return then(m, values)
+		}
+		m.evalThen(exps[len(values)], env, func(m *machine, value Expression) error {
+			// copy the values, the frame may be resumed again by a continuation
+			return next(m, append(values[:len(values):len(values)], value))
+		})
+		return nil
+	}
+	return next(m, make([]Expression, 0, len(exps)))
+}
+
+// evalSequence evaluates the expressions in order, the last one in the tail position.
+func (m *machine) evalSequence(exps []Expression, env *Env) {
+	if len(exps) == 0 {
+		m.ret(UndefObj)
+		return
+	}
+	if len(exps) > 1 {
+		m.push(func(m *machine, _ Expression) error {
+			m.evalSequence(exps[1:], env)
+			return nil
+		})
+	}
+	m.eval(exps[0], env)
+}
+
+// apply calls the procedure with the evaluated arguments.
+func (m *machine) apply(procedure Expression, args []Expression) error {
+	switch p := procedure.(type) {
+	case Function:
+		ret, err := p.Call(args...)
+		if err != nil {
+			return err
+		}
+		m.ret(ret)
+	case ControlFunction:
+		if err := p.validateArgCount(args...); err != nil {
+			return err
+		}
+		return p.control(m, args...)
+	case *LambdaProcess:
+		return m.bindLambda(p, args, func(m *machine, env *Env) error {
+			m.evalBody(p.body, env)
+			return nil
+		})
+	case *CaseLambda:
+		clause, err := p.clause(len(args))
+		if err != nil {
+			return err
+		}
+		return m.apply(clause, args)
+	case *Continuation:
+		return p.resume(m, args)
+	default:
+		return fmt.Errorf("%v is not callable", valueToString(procedure))
+	}
+	return nil
+}
+
+// winder is the dynamic extent entered by dynamic-wind.
+type winder struct {
+	before, after Expression
+	parent        *winder
+}
+
+func (w *winder) depth() int {
+	n := 0
+	for ; w != nil; w = w.parent {
+		n++
+	}
+	return n
+}
+
+// commonWinder returns the innermost dynamic extent containing both a and b.
+func commonWinder(a, b *winder) *winder {
+	da, db := a.depth(), b.depth()
+	for ; da > db; da-- {
+		a = a.parent
+	}
+	for ; db > da; db-- {
+		b = b.parent
+	}
+	for a != b {
+		a, b = a.parent, b.parent
+	}
+	return a
+}
+
+// rewind leaves the dynamic extents running the after thunks from the innermost, then enters the extents of to running
+// the before thunks from the outermost, and calls then at last.
+func (m *machine) rewind(to *winder, then func(m *machine) error) error {
+	common := commonWinder(m.winders, to)
+	if m.winders != common {
+		w := m.winders
+		m.winders = w.parent
+		m.push(func(m *machine, _ Expression) error {
+			return m.rewind(to, then)
+		})
+		return m.apply(w.after, nil)
+	}
+	if to != common {
+		w := to
+		for w.parent != common {
+			w = w.parent
+		}
+		m.push(func(m *machine, _ Expression) error {
+			m.winders = w
+			return m.rewind(to, then)
+		})
+		return m.apply(w.before, nil)
+	}
+	return then(m)
+}
+
+// Continuation is the first class continuation captured by call/cc. Should only use with pointer.
+type Continuation struct {
+	k        *continuation
+	winders  *winder
+	handlers *exceptionHandler
+	// run is the machine capturing the continuation
+	run *machine
+	// escapeOnly continuation created by call/ec expires when the extent of the call/ec exits
+	escapeOnly, expired bool
+}
+
+// String returns the string representing the *Continuation.
+func (c *Continuation) String() string {
+	return "#[Continuation]"
+}
+
+// IsContinuation checks whether the expression value is a *Continuation.
+func IsContinuation(exp Expression) bool {
+	_, ok := exp.(*Continuation)
+	return ok
+}
+
+// resume abandons the current continuation of the machine and returns the value to c. When c is captured by an outer
+// machine which is still running, the go stack is unwound to that machine first.
+func (c *Continuation) resume(m *machine, args []Expression) error {
+	value := makeValues(args...)
+	if c.escapeOnly {
+		if c.expired {
+			return errors.New("escape continuation called outside of its extent")
+		}
+		c.expired = true
+	}
+	if c.run != m && !c.run.done {
+		return &continuationInvoked{target: c, value: value}
+	}
+	return c.enter(m, value)
+}
+
+// enter rewinds the dynamic extents of the machine to those of c and returns the value to c.
+func (c *Continuation) enter(m *machine, value Expression) error {
+	return m.rewind(c.winders, func(m *machine) error {
+		m.k = c.k
+		m.handlers = c.handlers
+		m.ret(value)
+		return nil
+	})
+}
+
+// continuationInvoked is returned as error to unwind the go stack to the machine where the target was captured.
+type continuationInvoked struct {
+	target *Continuation
+	value  Expression
+}
+
+func (c *continuationInvoked) Error() string {
+	return "continuation invoked outside of the evaluation capturing it"
+}
+
+type controlFunc func(m *machine, args ...Expression) error
+
+// ControlFunction is the builtin procedure accessing the continuation of the evaluation, such as call/cc.
+type ControlFunction struct {
+	Function
+	control controlFunc
+}
+
+// NewControlFunction constructs the ControlFunction with the argument count limit like NewFunction.
+func NewControlFunction(name string, control controlFunc, min, max int) ControlFunction {
+	return ControlFunction{Function: Function{name: name, minArgs: min, maxArgs: max}, control: control}
+}
+
+// callCC calls the procedure with the current continuation.
+func callCC(m *machine, args ...Expression) error {
+	return m.apply(args[0], []Expression{&Continuation{k: m.k, winders: m.winders, handlers: m.handlers, run: m}})
+}
+
+// escapeContinuation captures the current continuation which can only be called to exit before the current
+// evaluation returns.
+func (m *machine) escapeContinuation() *Continuation {
+	c := &Continuation{k: m.k, winders: m.winders, handlers: m.handlers, run: m, escapeOnly: true}
+	m.push(func(m *machine, value Expression) error {
+		c.expired = true
+		m.ret(value)
+		return nil
+	})
+	return c
+}
+
+// callEC calls the procedure with the escape-only continuation.
+func callEC(m *machine, args ...Expression) error {
+	return m.apply(args[0], []Expression{m.escapeContinuation()})
+}
+
+// evalLetEC binds the escape-only continuation to the variable and evaluates the body, (let/ec k body ...).
+func evalLetEC(m *machine, args []Expression, env *Env) error {
+	if len(args) < 2 {
+		return errors.New("let/ec: syntax error (requires variable and body)")
+	}
+	sym, err := transExpressionToSymbol(args[0])
+	if err != nil {
+		return err
+	}
+	newEnv := &Env{outer: env, frame: make(map[Symbol]Expression)}
+	newEnv.Set(sym, m.escapeContinuation())
+	m.evalBody(args[1:], newEnv)
+	return nil
+}
+
+// dynamicWind calls the thunk in the dynamic extent of before and after, the before thunk runs whenever the extent is
+// entered and the after thunk whenever it is left, including by continuations and errors.
+func dynamicWind(m *machine, args ...Expression) error {
+	before, thunk, after := args[0], args[1], args[2]
+	outer := m.winders
+	m.push(func(m *machine, _ Expression) error {
+		m.winders = &winder{before: before, after: after, parent: outer}
+		m.push(func(m *machine, value Expression) error {
+			m.winders = outer
+			m.push(func(m *machine, _ Expression) error {
+				m.ret(value)
+				return nil
+			})
+			return m.apply(after, nil)
+		})
+		return m.apply(thunk, nil)
+	})
+	return m.apply(before, nil)
+}
@@ -0,0 +1,122 @@
+package goscheme
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCallCC(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"(+ 1 (call/cc (lambda (k) (+ 10 (k 2)))))", "3"},
+		{"(call-with-current-continuation (lambda (k) 1))", "1"},
+		{"(define (find-first pred lst) (call/cc (lambda (return)" +
+			" (define (walk l) (if (null? l) #f (begin (if (pred (car l)) (return (car l)) #f) (walk (cdr l)))))" +
+			" (walk lst)))) (find-first (lambda (x) (> x 2)) '(1 2 3 4))", "3"},
+		{"(let ((k #f) (n 0)) (set! n (+ (call/cc (lambda (c) (set! k c) 1)) n)) (if (< n 10) (k n) n))", "16"},
+		{"(define r #f) (define x (+ 100 (call/cc (lambda (k) (set! r k) 1)))) x", "101"},
+		{"(r 5) x", "105"},
+		{"(define (make-gen lst) (define state (vector #f #f))" +
+			" (define (walk l) (if (null? l) ((vector-ref state 0) 'done)" +
+			"  (begin (call/cc (lambda (k) (vector-set! state 1 k) ((vector-ref state 0) (car l)))) (walk (cdr l)))))" +
+			" (lambda () (call/cc (lambda (r) (vector-set! state 0 r)" +
+			"  (if (vector-ref state 1) ((vector-ref state 1) #f) (walk lst))))))" +
+			"(define g (make-gen '(1 2 3))) (list (g) (g) (g) (g))", "(1 2 3 done)"},
+		{"(define g (make-gen '(a b))) (g)", "a"},
+		{"(g)", "b"},
+		{"(define fail-box (vector (lambda () 'exhausted)))" +
+			"(define (amb choices) (let ((prev (vector-ref fail-box 0))) (call/cc (lambda (k)" +
+			" (define (try cs) (if (null? cs) (begin (vector-set! fail-box 0 prev) (prev))" +
+			"  (begin (vector-set! fail-box 0 (lambda () (try (cdr cs)))) (k (car cs)))))" +
+			" (try choices)))))" +
+			"(let* ((a (amb '(1 2 3 4 5 6 7))) (b (amb '(1 2 3 4 5 6 7))) (c (amb '(1 2 3 4 5 6 7))))" +
+			" (if (and (< a b) (= (* c c) (+ (* a a) (* b b)))) (list a b c) ((vector-ref fail-box 0))))", "(3 4 5)"},
+		{"(define h (make-hash-table)) (hash-table-set! h 'a 1) (hash-table-set! h 'b 2) (hash-table-set! h 'c 3)" +
+			"(call/cc (lambda (return) (hash-table-walk h (lambda (k v) (if (= v 2) (return k) #f))) 'none))", "b"},
+		{"(apply call/cc (list (lambda (k) (k 'applied))))", "applied"},
+		{"(define (count n) (if (= n 0) 0 (+ 1 (count (- n 1))))) (count 10000)", "10000"},
+		// continuations captured in callbacks and unquotes include the rest of the computation
+		{"(define kk #f) (define r '())" +
+			"(let ((x `(a ,(call/cc (lambda (c) (set! kk c) 1))))) (set! r (cons x r)) (if (< (list-length r) 2) (kk 2) r))",
+			"((a 2) (a 1))"},
+		{"(define kk #f) (define r '())" +
+			"(let ((x `(a ,@(call/cc (lambda (c) (set! kk c) '(1))) . #(,(+ 1 2))))) (set! r (cons x r))" +
+			" (if (< (list-length r) 2) (kk '(x y)) r))", "((a x y . #(3)) (a 1 . #(3)))"},
+		{"(define kk #f) (define r '()) (define h (make-hash-table)) (hash-table-set! h 'a 1) (hash-table-set! h 'b 2)" +
+			"(begin (hash-table-walk h (lambda (k v) (set! r (cons (call/cc (lambda (c) (if (= v 1) (set! kk c)) k)) r))))" +
+			" (if (< (list-length r) 3) (kk 'again) r))", "(b again b a)"},
+		{"(member 2.0 '(1 2 3) (lambda (a b) (call/cc (lambda (k) (k (= a b))))))", "(2 3)"},
+		{"(define p (delay (+ 1 (call/cc (lambda (k) (k 1)))))) (list (force p) (force p) (force 3))", "(2 2 3)"},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+
+	ret, err := EvalAll(strToToken("(call/cc call/cc)"), env)
+	assert.Nil(t, err)
+	assert.True(t, IsContinuation(ret))
+
+	for _, input := range []string{"(call/cc 1)", "(call/cc)"} {
+		_, err := EvalAll(strToToken(input), env)
+		assert.NotNil(t, err, input)
+	}
+}
+
+func TestDynamicWind(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"(define trace '()) (define (add x) (set! trace (cons x trace)))" +
+			"(dynamic-wind (lambda () (add 'before)) (lambda () (add 'during) 'result) (lambda () (add 'after)))", "result"},
+		{"trace", "(after during before)"},
+		{"(set! trace '())" +
+			"(call/cc (lambda (k) (dynamic-wind (lambda () (add 'in)) (lambda () (k 'escaped) (add 'never)) (lambda () (add 'out)))))",
+			"escaped"},
+		{"trace", "(out in)"},
+		{"(set! trace '()) (define box (vector #f))" +
+			"(dynamic-wind (lambda () (add 'in)) (lambda () (call/cc (lambda (k) (vector-set! box 0 k))) (add 'body))" +
+			" (lambda () (add 'out)))" +
+			"(define n 0)", ""},
+		{"(if (= n 0) (begin (set! n 1) ((vector-ref box 0) #f)) #f) trace", "(out body in out body in)"},
+		{"(set! trace '())" +
+			"(dynamic-wind (lambda () (add 'outer-in))" +
+			" (lambda () (call/cc (lambda (k) (dynamic-wind (lambda () (add 'inner-in)) (lambda () (k 1)) (lambda () (add 'inner-out))))))" +
+			" (lambda () (add 'outer-out))) trace", "(outer-out inner-out inner-in outer-in)"},
+		{"(set! trace '()) (define h (make-hash-table)) (hash-table-set! h 'a 1)" +
+			"(call/cc (lambda (k) (hash-table-walk h (lambda (key v)" +
+			" (dynamic-wind (lambda () #f) (lambda () (k key)) (lambda () (add 'released)))))))", "a"},
+		{"trace", "(released)"},
+		{"(+ 1 (call/ec (lambda (k) (+ 10 (k 2)))))", "3"},
+		{"(call-with-escape-continuation (lambda (k) 5))", "5"},
+		{"(let/ec k (+ 1 (k 42)))", "42"},
+		{"(let/ec k 1 2)", "2"},
+		{"(set! trace '()) (let/ec k (dynamic-wind (lambda () (add 'a)) (lambda () (k 'x)) (lambda () (add 'b))))", "x"},
+		{"trace", "(b a)"},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		if c.expected != "" {
+			assert.Equal(t, c.expected, valueToString(ret), c.input)
+		}
+	}
+
+	_, err := EvalAll(strToToken("(set! trace '()) (dynamic-wind (lambda () #f) (lambda () (car 1)) (lambda () (add 'cleanup)))"), env)
+	assert.NotNil(t, err)
+	ret, _ := EvalAll(strToToken("trace"), env)
+	assert.Equal(t, "(cleanup)", valueToString(ret))
+
+	for _, input := range []string{"(dynamic-wind (lambda () 1) (lambda () 2))", "(let/ec)", "(let/ec 1 2)",
+		"(define saved (vector #f)) (let/ec k (vector-set! saved 0 k) 1) ((vector-ref saved 0) 2)",
+		"(call/ec (lambda (k) (k 1) (k 2))) ((call/ec (lambda (k) k)) 1)"} {
+		_, err := EvalAll(strToToken(input), env)
+		assert.NotNil(t, err, input)
+	}
+}
@@ -3,7 +3,12 @@ package goscheme
 import (
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"math/cmplx"
 	"os"
+	"strings"
+	"unicode"
 )
 
 // Env represents the context of code.
@@ -12,16 +17,70 @@ type Env struct {
 	frame map[Symbol]Expression
 }
 
+// unassigned is the value of the variables bound but not yet defined, such as the letrec variables and the internal
+// definitions of a body before their initial values are evaluated.
+type unassigned struct{}
+
+// unassignedObj is the common unassigned object.
+var unassignedObj = unassigned{}
+
 // Find search all the relative environments to find the variable matching symbol.
 func (e *Env) Find(symbol Symbol) (Expression, error) {
-	ret, ok := e.frame[symbol]
-	if ok {
-		return ret, nil
-	}
-	if e.outer == nil {
+	ret, ok := e.lookup(symbol)
+	if !ok {
 		return nil, fmt.Errorf("symbol %v unbound", symbol)
 	}
-	return e.outer.Find(symbol)
+	return assigned(symbol, ret)
+}
+
+// assigned returns the value of the variable, the variable not yet defined is an error.
+func assigned(symbol Symbol, value Expression) (Expression, error) {
+	if value == unassignedObj {
+		return nil, fmt.Errorf("variable %v used before its definition", symbol)
+	}
+	return value, nil
+}
+
+func (e *Env) lookup(symbol Symbol) (Expression, bool) {
+	for env := e; env != nil; env = env.outer {
+		if ret, ok := env.frame[symbol]; ok {
+			return ret, true
+		}
+	}
+	return nil, false
+}
+
+// findSymbol finds the value of the symbol expression. The identifier inserted by macro expansion is looked up by its
+// key if bound by the expansion, otherwise by its original name in the environment of the macro definition.
+func (e *Env) findSymbol(exp Expression) (Expression, error) {
+	id, ok := exp.(*identifier)
+	if !ok {
+		s, _ := exp.(Symbol)
+		return e.Find(s)
+	}
+	if ret, ok := e.lookup(id.key); ok {
+		return assigned(id.key, ret)
+	}
+	return id.env.findSymbol(id.name)
+}
+
+// binding returns the environment binding the symbol expression and the symbol bound there, the environment is nil
+// when the symbol is unbound. The identifier inserted by macro expansion is resolved like findSymbol.
+func (e *Env) binding(exp Expression) (*Env, Symbol) {
+	id, ok := exp.(*identifier)
+	if !ok {
+		s, _ := exp.(Symbol)
+		for env := e; env != nil; env = env.outer {
+			if _, ok := env.frame[s]; ok {
+				return env, s
+			}
+		}
+		return nil, s
+	}
+	if env, s := e.binding(id.key); env != nil {
+		return env, s
+	}
+	return id.env.binding(id.name)
 }
 
 // Set a symbol and its value in current environment
@@ -58,44 +117,43 @@ func exitFunc(_ ...Expression) (Expression, error) {
 }
 
 func addFunc(args ...Expression) (Expression, error) {
-	var ret Number
+	var ret Number = Integer(0)
 	for _, arg := range args {
 		num, err := expressionToNumber(arg)
 		if err != nil {
-			return 0, err
+			return UndefObj, err
 		}
-		ret = ret + num
+		ret = numAdd(ret, num)
 	}
 	return ret, nil
 }
 
 func minusFunc(args ...Expression) (Expression, error) {
-	var ret Number
 	ret, err := expressionToNumber(args[0])
 	if err != nil {
 		return UndefObj, err
 	}
-	for i, arg := range args {
-		if i == 0 {
-			continue
-		}
+	if len(args) == 1 {
+		return numNegate(ret), nil
+	}
+	for _, arg := range args[1:] {
 		num, err := expressionToNumber(arg)
 		if err != nil {
 			return UndefObj, err
 		}
-		ret = ret - num
+		ret = numSub(ret, num)
 	}
 	return ret, nil
 }
 
 func plusFunc(args ...Expression) (Expression, error) {
-	var ret Number = 1
+	var ret Number = Integer(1)
 	for _, arg := range args {
 		num, err := expressionToNumber(arg)
 		if err != nil {
 			return UndefObj, err
 		}
-		ret = ret * num
+		ret = numMul(ret, num)
 	}
 	return ret, nil
 }
@@ -106,101 +164,631 @@ func divFunc(args ...Expression) (Expression, error) {
 		if err != nil {
 			return UndefObj, err
 		}
-		return 1 / num, nil
+		return numDiv(Integer(1), num)
+	}
+	ret, err := expressionToNumber(args[0])
+	if err != nil {
+		return UndefObj, err
 	}
-	var ret Number
-	for i, arg := range args {
+	for _, arg := range args[1:] {
 		num, err := expressionToNumber(arg)
 		if err != nil {
 			return UndefObj, err
 		}
-		if i == 0 {
-			ret = num
-			continue
+		ret, err = numDiv(ret, num)
+		if err != nil {
+			return UndefObj, err
 		}
-		ret = ret / num
 	}
 	return ret, nil
 }
 
 func eqlFunc(args ...Expression) (Expression, error) {
-	if args[0] == args[1] {
-		return true, nil
+	for i := 0; i < len(args)-1; i++ {
+		op1, ok1 := args[i].(Number)
+		op2, ok2 := args[i+1].(Number)
+		if ok1 && ok2 {
+			if !numEqual(op1, op2) {
+				return false, nil
+			}
+		} else if !isEqv(args[i], args[i+1]) {
+			return false, nil
+		}
 	}
-	return false, nil
+	return true, nil
+}
+
+// compareNumbers compares each adjacent pair of the numeric arguments and checks the results with the test function.
+func compareNumbers(args []Expression, test func(c int) bool) (Expression, error) {
+	nums, err := realArgs(args)
+	if err != nil {
+		return UndefObj, err
+	}
+	for i := 0; i < len(nums)-1; i++ {
+		c, ok := numCompare(nums[i], nums[i+1])
+		if !ok || !test(c) {
+			return false, nil
+		}
+	}
+	return true, nil
 }
 
 func lessFunc(args ...Expression) (Expression, error) {
-	op1, err := expressionToNumber(args[0])
+	return compareNumbers(args, func(c int) bool { return c < 0 })
+}
+
+func greaterFunc(args ...Expression) (Expression, error) {
+	return compareNumbers(args, func(c int) bool { return c > 0 })
+}
+
+func lessEqualFunc(args ...Expression) (Expression, error) {
+	return compareNumbers(args, func(c int) bool { return c <= 0 })
+}
+
+func greatEqualFunc(args ...Expression) (Expression, error) {
+	return compareNumbers(args, func(c int) bool { return c >= 0 })
+}
+
+func isNumberFunc(args ...Expression) (Expression, error) {
+	_, ok := args[0].(Number)
+	return ok, nil
+}
+
+func isIntegerFunc(args ...Expression) (Expression, error) {
+	num, ok := args[0].(Number)
+	return ok && isIntegerNumber(num), nil
+}
+
+func isExactIntegerFunc(args ...Expression) (Expression, error) {
+	switch args[0].(type) {
+	case Integer, *BigInt:
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func isNaNFunc(args ...Expression) (Expression, error) {
+	nums, err := realArgs(args)
 	if err != nil {
 		return UndefObj, err
 	}
-	op2, err := expressionToNumber(args[1])
+	return math.IsNaN(toFloat(nums[0])), nil
+}
+
+func isInfiniteFunc(args ...Expression) (Expression, error) {
+	nums, err := realArgs(args)
 	if err != nil {
 		return UndefObj, err
 	}
-	if op1 < op2 {
-		return true, nil
+	return math.IsInf(toFloat(nums[0]), 0), nil
+}
+
+func isFiniteFunc(args ...Expression) (Expression, error) {
+	nums, err := realArgs(args)
+	if err != nil {
+		return UndefObj, err
 	}
-	return false, nil
+	f := toFloat(nums[0])
+	return !math.IsInf(f, 0) && !math.IsNaN(f), nil
 }
 
-func greaterFunc(args ...Expression) (Expression, error) {
-	op1, err := expressionToNumber(args[0])
+func isZeroFunc(args ...Expression) (Expression, error) {
+	num, err := expressionToNumber(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	return numEqual(num, Integer(0)), nil
+}
+
+func isPositiveFunc(args ...Expression) (Expression, error) {
+	return compareNumbers([]Expression{args[0], Integer(0)}, func(c int) bool { return c > 0 })
+}
+
+func isNegativeFunc(args ...Expression) (Expression, error) {
+	return compareNumbers([]Expression{args[0], Integer(0)}, func(c int) bool { return c < 0 })
+}
+
+func isOddFunc(args ...Expression) (Expression, error) {
+	nums, err := integerArgs(args)
+	if err != nil {
+		return UndefObj, err
+	}
+	_, r, err := integerDivide(nums[0], Integer(2), true)
 	if err != nil {
 		return UndefObj, err
 	}
-	op2, err := expressionToNumber(args[1])
+	return !numEqual(r, Integer(0)), nil
+}
+
+func isEvenFunc(args ...Expression) (Expression, error) {
+	odd, err := isOddFunc(args...)
 	if err != nil {
 		return UndefObj, err
 	}
-	if op1 > op2 {
+	return !odd.(bool), nil
+}
+
+func isComplexFunc(args ...Expression) (Expression, error) {
+	return isNumberFunc(args...)
+}
+
+func isRealFunc(args ...Expression) (Expression, error) {
+	num, ok := args[0].(Number)
+	return ok && isRealNumber(num), nil
+}
+
+func isRationalFunc(args ...Expression) (Expression, error) {
+	switch v := args[0].(type) {
+	case Integer, *BigInt, *Rational:
 		return true, nil
+	case Real:
+		f := float64(v)
+		return !math.IsInf(f, 0) && !math.IsNaN(f), nil
+	default:
+		return false, nil
 	}
-	return false, nil
 }
 
-func lessEqualFunc(args ...Expression) (Expression, error) {
-	op1, err := expressionToNumber(args[0])
+func numeratorFunc(args ...Expression) (Expression, error) {
+	num, err := expressionToNumber(args[0])
 	if err != nil {
 		return UndefObj, err
 	}
-	op2, err := expressionToNumber(args[1])
+	exact, err := exactNumber(num)
 	if err != nil {
 		return UndefObj, err
 	}
-	if op1 <= op2 {
-		return true, nil
+	ret := normalizeBigInt(new(big.Int).Set(toRat(exact).Num()))
+	if !num.IsExact() {
+		return inexactNumber(ret), nil
 	}
-	return false, nil
+	return ret, nil
 }
 
-func greatEqualFunc(args ...Expression) (Expression, error) {
-	op1, err := expressionToNumber(args[0])
+func denominatorFunc(args ...Expression) (Expression, error) {
+	num, err := expressionToNumber(args[0])
 	if err != nil {
 		return UndefObj, err
 	}
-	op2, err := expressionToNumber(args[1])
+	exact, err := exactNumber(num)
 	if err != nil {
 		return UndefObj, err
 	}
-	if op1 >= op2 {
-		return true, nil
+	ret := normalizeBigInt(new(big.Int).Set(toRat(exact).Denom()))
+	if !num.IsExact() {
+		return inexactNumber(ret), nil
+	}
+	return ret, nil
+}
+
+// rationalizeFunc returns the simplest rational number differing from the first argument by no more than the second.
+func rationalizeFunc(args ...Expression) (Expression, error) {
+	x, err := expressionToNumber(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	y, err := expressionToNumber(args[1])
+	if err != nil {
+		return UndefObj, err
+	}
+	ex, err := exactNumber(x)
+	if err != nil {
+		return UndefObj, err
+	}
+	ey, err := exactNumber(y)
+	if err != nil {
+		return UndefObj, err
+	}
+	delta := new(big.Rat).Abs(toRat(ey))
+	lo := new(big.Rat).Sub(toRat(ex), delta)
+	hi := new(big.Rat).Add(toRat(ex), delta)
+	ret := normalizeRat(simplestRational(lo, hi))
+	if !x.IsExact() || !y.IsExact() {
+		return inexactNumber(ret), nil
+	}
+	return ret, nil
+}
+
+// realArgs converts the arguments to real numbers.
+func realArgs(args []Expression) ([]Number, error) {
+	ret := make([]Number, 0, len(args))
+	for _, arg := range args {
+		num, err := expressionToNumber(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !isRealNumber(num) {
+			return nil, fmt.Errorf("%v is not a real number", valueToString(num))
+		}
+		ret = append(ret, num)
+	}
+	return ret, nil
+}
+
+// integerArgs converts the arguments to integers.
+func integerArgs(args []Expression) ([]Number, error) {
+	ret := make([]Number, 0, len(args))
+	for _, arg := range args {
+		num, err := expressionToNumber(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !isIntegerNumber(num) {
+			return nil, fmt.Errorf("%v is not an integer", valueToString(num))
+		}
+		ret = append(ret, num)
+	}
+	return ret, nil
+}
+
+// integerDivideFunc returns the builtin function dividing two integers and picking the results with pick.
+func integerDivideFunc(floor bool, pick func(q, r Number) Expression) commonFunction {
+	return func(args ...Expression) (Expression, error) {
+		nums, err := integerArgs(args)
+		if err != nil {
+			return UndefObj, err
+		}
+		q, r, err := integerDivide(nums[0], nums[1], floor)
+		if err != nil {
+			return UndefObj, err
+		}
+		return pick(q, r), nil
+	}
+}
+
+func pickQuotient(q, _ Number) Expression {
+	return q
+}
+
+func pickRemainder(_, r Number) Expression {
+	return r
+}
+
+func pickBoth(q, r Number) Expression {
+	return makeValues(q, r)
+}
+
+func gcdFunc(args ...Expression) (Expression, error) {
+	nums, err := integerArgs(args)
+	if err != nil {
+		return UndefObj, err
+	}
+	var ret Number = Integer(0)
+	for _, num := range nums {
+		ret = gcdNumber(ret, num)
+	}
+	return ret, nil
+}
+
+func lcmFunc(args ...Expression) (Expression, error) {
+	nums, err := integerArgs(args)
+	if err != nil {
+		return UndefObj, err
+	}
+	var ret Number = Integer(1)
+	for _, num := range nums {
+		if numEqual(num, Integer(0)) {
+			return numMul(ret, Integer(0)), nil
+		}
+		ret, _, _ = integerDivide(numAbs(numMul(ret, num)), gcdNumber(ret, num), false)
+	}
+	return ret, nil
+}
+
+func absFunc(args ...Expression) (Expression, error) {
+	if _, err := realArgs(args); err != nil {
+		return UndefObj, err
+	}
+	return magnitudeFunc(args...)
+}
+
+// extremum returns the argument which passes the test when comparing with all the others.
+// The result is inexact if any of the arguments is inexact.
+func extremum(args []Expression, test func(c int) bool) (Expression, error) {
+	nums, err := realArgs(args)
+	if err != nil {
+		return UndefObj, err
+	}
+	ret, exact := nums[0], nums[0].IsExact()
+	for _, num := range nums[1:] {
+		exact = exact && num.IsExact()
+		if c, ok := numCompare(num, ret); !ok {
+			ret = Real(math.NaN())
+		} else if test(c) {
+			ret = num
+		}
+	}
+	if !exact {
+		return inexactNumber(ret), nil
+	}
+	return ret, nil
+}
+
+func minFunc(args ...Expression) (Expression, error) {
+	return extremum(args, func(c int) bool { return c < 0 })
+}
+
+func maxFunc(args ...Expression) (Expression, error) {
+	return extremum(args, func(c int) bool { return c > 0 })
+}
+
+func squareFunc(args ...Expression) (Expression, error) {
+	num, err := expressionToNumber(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	return numMul(num, num), nil
+}
+
+func exptFunc(args ...Expression) (Expression, error) {
+	base, err := expressionToNumber(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	exponent, err := expressionToNumber(args[1])
+	if err != nil {
+		return UndefObj, err
+	}
+	return exptNumber(base, exponent)
+}
+
+// transcendentalFunc returns the builtin function computing realFn for real arguments within domain and complexFn for
+// the others.
+func transcendentalFunc(realFn func(float64) float64, complexFn func(complex128) complex128,
+	domain func(float64) bool) commonFunction {
+	return func(args ...Expression) (Expression, error) {
+		num, err := expressionToNumber(args[0])
+		if err != nil {
+			return UndefObj, err
+		}
+		if isRealNumber(num) && (domain == nil || domain(toFloat(num))) {
+			return Real(realFn(toFloat(num))), nil
+		}
+		return normalizeComplex(complexFn(toComplex(num))), nil
+	}
+}
+
+func nonNegative(f float64) bool {
+	return f >= 0 || math.IsNaN(f)
+}
+
+func withinUnit(f float64) bool {
+	return (f >= -1 && f <= 1) || math.IsNaN(f)
+}
+
+var (
+	expFunc  = transcendentalFunc(math.Exp, cmplx.Exp, nil)
+	sinFunc  = transcendentalFunc(math.Sin, cmplx.Sin, nil)
+	cosFunc  = transcendentalFunc(math.Cos, cmplx.Cos, nil)
+	tanFunc  = transcendentalFunc(math.Tan, cmplx.Tan, nil)
+	asinFunc = transcendentalFunc(math.Asin, cmplx.Asin, withinUnit)
+	acosFunc = transcendentalFunc(math.Acos, cmplx.Acos, withinUnit)
+	lnFunc   = transcendentalFunc(math.Log, cmplx.Log, nonNegative)
+)
+
+func logFunc(args ...Expression) (Expression, error) {
+	ret, err := lnFunc(args[0])
+	if err != nil || len(args) == 1 {
+		return ret, err
+	}
+	base, err := lnFunc(args[1])
+	if err != nil {
+		return UndefObj, err
+	}
+	return numDiv(ret.(Number), base.(Number))
+}
+
+func atanFunc(args ...Expression) (Expression, error) {
+	if len(args) == 1 {
+		return transcendentalFunc(math.Atan, cmplx.Atan, nil)(args...)
+	}
+	nums, err := realArgs(args)
+	if err != nil {
+		return UndefObj, err
+	}
+	return Real(math.Atan2(toFloat(nums[0]), toFloat(nums[1]))), nil
+}
+
+func exactIntegerSqrtFunc(args ...Expression) (Expression, error) {
+	n, ok := args[0].(Number)
+	if !ok || !n.IsExact() || !isIntegerNumber(n) || toBigInt(n).Sign() < 0 {
+		return UndefObj, fmt.Errorf("exact-integer-sqrt: %v is not an exact non-negative integer", valueToString(args[0]))
+	}
+	root := new(big.Int).Sqrt(toBigInt(n))
+	rest := new(big.Int).Sub(toBigInt(n), new(big.Int).Mul(root, root))
+	return makeValues(normalizeBigInt(root), normalizeBigInt(rest)), nil
+}
+
+// roundFunc returns the builtin function rounding a real number with the mode.
+func roundFunc(mode string) commonFunction {
+	return func(args ...Expression) (Expression, error) {
+		nums, err := realArgs(args)
+		if err != nil {
+			return UndefObj, err
+		}
+		return roundNumber(nums[0], mode), nil
+	}
+}
+
+// radixArg returns the radix specified by the optional argument at index i.
+func radixArg(args []Expression, i int) (int, error) {
+	if len(args) <= i {
+		return 10, nil
+	}
+	switch args[i] {
+	case Integer(2), Integer(8), Integer(10), Integer(16):
+		return int(args[i].(Integer)), nil
+	}
+	return 0, fmt.Errorf("%v is not a valid radix, must be one of 2, 8, 10 and 16", valueToString(args[i]))
+}
+
+func numberToStringFunc(args ...Expression) (Expression, error) {
+	num, err := expressionToNumber(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	radix, err := radixArg(args, 1)
+	if err != nil {
+		return UndefObj, err
+	}
+	s, err := numberToString(num, radix)
+	return NewMutableString(s), err
+}
+
+func stringToNumberFunc(args ...Expression) (Expression, error) {
+	s, err := expressionToGoString(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	radix, err := radixArg(args, 1)
+	if err != nil {
+		return UndefObj, err
+	}
+	if num, ok := parseNumber(string(s), radix); ok {
+		return num, nil
 	}
 	return false, nil
 }
 
+func makeRectangularFunc(args ...Expression) (Expression, error) {
+	nums, err := realArgs(args)
+	if err != nil {
+		return UndefObj, err
+	}
+	return makeRectangular(nums[0], nums[1]), nil
+}
+
+func makePolarFunc(args ...Expression) (Expression, error) {
+	nums, err := realArgs(args)
+	if err != nil {
+		return UndefObj, err
+	}
+	return makePolar(nums[0], nums[1]), nil
+}
+
+func realPartFunc(args ...Expression) (Expression, error) {
+	num, err := expressionToNumber(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	if c, ok := num.(Complex); ok {
+		return Real(real(c)), nil
+	}
+	return num, nil
+}
+
+func imagPartFunc(args ...Expression) (Expression, error) {
+	num, err := expressionToNumber(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	if c, ok := num.(Complex); ok {
+		return Real(imag(c)), nil
+	}
+	return Integer(0), nil
+}
+
+func magnitudeFunc(args ...Expression) (Expression, error) {
+	num, err := expressionToNumber(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	if c, ok := num.(Complex); ok {
+		return Real(cmplx.Abs(complex128(c))), nil
+	}
+	return numAbs(num), nil
+}
+
+func angleFunc(args ...Expression) (Expression, error) {
+	num, err := expressionToNumber(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	if c, ok := num.(Complex); ok {
+		return Real(cmplx.Phase(complex128(c))), nil
+	}
+	if c, _ := numCompare(num, Integer(0)); c < 0 {
+		return Real(math.Pi), nil
+	}
+	if num.IsExact() {
+		return Integer(0), nil
+	}
+	return Real(0), nil
+}
+
+func sqrtFunc(args ...Expression) (Expression, error) {
+	num, err := expressionToNumber(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	return sqrtNumber(num), nil
+}
+
+func isExactFunc(args ...Expression) (Expression, error) {
+	num, err := expressionToNumber(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	return num.IsExact(), nil
+}
+
+func isInexactFunc(args ...Expression) (Expression, error) {
+	num, err := expressionToNumber(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	return !num.IsExact(), nil
+}
+
+func exactFunc(args ...Expression) (Expression, error) {
+	num, err := expressionToNumber(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	return exactNumber(num)
+}
+
+func inexactFunc(args ...Expression) (Expression, error) {
+	num, err := expressionToNumber(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	return inexactNumber(num), nil
+}
+
 func displayFunc(args ...Expression) (Expression, error) {
 	exp := args[0]
 	switch v := exp.(type) {
 	case String:
 		fmt.Print(string(v))
+	case Char:
+		fmt.Print(string(v))
+	case *MutableString:
+		fmt.Print(string(v.runes))
+	case Symbol:
+		fmt.Print(v.String())
 	default:
 		fmt.Printf("%v", valueToString(v))
 	}
 	return UndefObj, nil
 }
 
+// writeFunc prints the external representation of the value which can be read back by read.
+func writeFunc(args ...Expression) (Expression, error) {
+	fmt.Print(valueToString(args[0]))
+	return UndefObj, nil
+}
+
+func eofObjectFunc(args ...Expression) (Expression, error) {
+	return EOFObj, nil
+}
+
+func isEOFObjectFunc(args ...Expression) (Expression, error) {
+	_, ok := args[0].(EOFType)
+	return ok, nil
+}
+
 func displaylnFunc(args ...Expression) (Expression, error) {
 	ret, err := displayFunc(args...)
 	fmt.Println()
@@ -211,48 +799,39 @@ func isNullFunc(args ...Expression) (Expression, error) {
 	return IsNullExp(args[0]), nil
 }
 
-func isStringFunc(args ...Expression) (Expression, error) {
-	exp := args[0]
-	return IsString(exp), nil
-}
-
 func notFunc(args ...Expression) (Expression, error) {
 	return !IsTrue(args[0]), nil
 }
 
 // concatFunc concat the strings
 func concatFunc(args ...Expression) (Expression, error) {
-	var ret String
+	var ret string
 	for _, arg := range args {
 		v := arg
-		s, ok := v.(String)
+		s, ok := stringValue(v)
 		if !ok {
 			return UndefObj, fmt.Errorf("argument %v is not a String", v)
 		}
 		ret += s
 	}
-	return ret, nil
+	return NewMutableString(ret), nil
 }
 
 func checkThunkFunc(args ...Expression) (Expression, error) {
 	return IsThunk(args[0]), nil
 }
 
-func forceFunc(args ...Expression) (Expression, error) {
-	return ActualValue(args[0])
-}
-
 var builtinFunctions = map[Symbol]Function{
 	"exit":      NewFunction("exit", exitFunc, 0, 0),
 	"+":         NewFunction("+", addFunc, 1, -1),
 	"-":         NewFunction("-", minusFunc, 1, -1),
 	"*":         NewFunction("*", plusFunc, 1, -1),
 	"/":         NewFunction("/", divFunc, 1, -1),
-	"=":         NewFunction("=", eqlFunc, 2, 2),
-	"<":         NewFunction("<", lessFunc, 2, 2),
-	">":         NewFunction(">", greaterFunc, 2, 2),
-	"<=":        NewFunction("<=", lessEqualFunc, 2, 2),
-	">=":        NewFunction(">=", greatEqualFunc, 2, 2),
+	"=":         NewFunction("=", eqlFunc, 1, -1),
+	"<":         NewFunction("<", lessFunc, 1, -1),
+	">":         NewFunction(">", greaterFunc, 1, -1),
+	"<=":        NewFunction("<=", lessEqualFunc, 1, -1),
+	">=":        NewFunction(">=", greatEqualFunc, 1, -1),
 	"display":   NewFunction("display", displayFunc, 1, 1),
 	"displayln": NewFunction("displayln", displaylnFunc, 1, 1),
 	"null?":     NewFunction("null?", isNullFunc, 1, 1),
@@ -269,7 +848,229 @@ var builtinFunctions = map[Symbol]Function{
 	"set-cdr!": NewFunction("set-cdr!", setCdrImpl, 2, 2),
 	"concat":   NewFunction("concat", concatFunc, 2, -1),
 	"thunk?":   NewFunction("thunk?", checkThunkFunc, 1, 1),
-	"force":    NewFunction("thunk?", forceFunc, 1, 1),
+
+	"number?":          NewFunction("number?", isNumberFunc, 1, 1),
+	"integer?":         NewFunction("integer?", isIntegerFunc, 1, 1),
+	"exact?":           NewFunction("exact?", isExactFunc, 1, 1),
+	"inexact?":         NewFunction("inexact?", isInexactFunc, 1, 1),
+	"exact":            NewFunction("exact", exactFunc, 1, 1),
+	"inexact":          NewFunction("inexact", inexactFunc, 1, 1),
+	"exact->inexact":   NewFunction("exact->inexact", inexactFunc, 1, 1),
+	"inexact->exact":   NewFunction("inexact->exact", exactFunc, 1, 1),
+	"complex?":         NewFunction("complex?", isComplexFunc, 1, 1),
+	"real?":            NewFunction("real?", isRealFunc, 1, 1),
+	"rational?":        NewFunction("rational?", isRationalFunc, 1, 1),
+	"numerator":        NewFunction("numerator", numeratorFunc, 1, 1),
+	"denominator":      NewFunction("denominator", denominatorFunc, 1, 1),
+	"rationalize":      NewFunction("rationalize", rationalizeFunc, 2, 2),
+	"make-rectangular": NewFunction("make-rectangular", makeRectangularFunc, 2, 2),
+	"make-polar":       NewFunction("make-polar", makePolarFunc, 2, 2),
+	"real-part":        NewFunction("real-part", realPartFunc, 1, 1),
+	"imag-part":        NewFunction("imag-part", imagPartFunc, 1, 1),
+	"magnitude":        NewFunction("magnitude", magnitudeFunc, 1, 1),
+	"angle":            NewFunction("angle", angleFunc, 1, 1),
+	"sqrt":             NewFunction("sqrt", sqrtFunc, 1, 1),
+
+	"exact-integer?":     NewFunction("exact-integer?", isExactIntegerFunc, 1, 1),
+	"nan?":               NewFunction("nan?", isNaNFunc, 1, 1),
+	"infinite?":          NewFunction("infinite?", isInfiniteFunc, 1, 1),
+	"finite?":            NewFunction("finite?", isFiniteFunc, 1, 1),
+	"zero?":              NewFunction("zero?", isZeroFunc, 1, 1),
+	"positive?":          NewFunction("positive?", isPositiveFunc, 1, 1),
+	"negative?":          NewFunction("negative?", isNegativeFunc, 1, 1),
+	"odd?":               NewFunction("odd?", isOddFunc, 1, 1),
+	"even?":              NewFunction("even?", isEvenFunc, 1, 1),
+	"quotient":           NewFunction("quotient", integerDivideFunc(false, pickQuotient), 2, 2),
+	"remainder":          NewFunction("remainder", integerDivideFunc(false, pickRemainder), 2, 2),
+	"modulo":             NewFunction("modulo", integerDivideFunc(true, pickRemainder), 2, 2),
+	"floor-quotient":     NewFunction("floor-quotient", integerDivideFunc(true, pickQuotient), 2, 2),
+	"floor-remainder":    NewFunction("floor-remainder", integerDivideFunc(true, pickRemainder), 2, 2),
+	"floor/":             NewFunction("floor/", integerDivideFunc(true, pickBoth), 2, 2),
+	"truncate-quotient":  NewFunction("truncate-quotient", integerDivideFunc(false, pickQuotient), 2, 2),
+	"truncate-remainder": NewFunction("truncate-remainder", integerDivideFunc(false, pickRemainder), 2, 2),
+	"truncate/":          NewFunction("truncate/", integerDivideFunc(false, pickBoth), 2, 2),
+	"gcd":                NewFunction("gcd", gcdFunc, 0, -1),
+	"lcm":                NewFunction("lcm", lcmFunc, 0, -1),
+	"abs":                NewFunction("abs", absFunc, 1, 1),
+	"min":                NewFunction("min", minFunc, 1, -1),
+	"max":                NewFunction("max", maxFunc, 1, -1),
+	"square":             NewFunction("square", squareFunc, 1, 1),
+	"expt":               NewFunction("expt", exptFunc, 2, 2),
+	"exp":                NewFunction("exp", expFunc, 1, 1),
+	"log":                NewFunction("log", logFunc, 1, 2),
+	"sin":                NewFunction("sin", sinFunc, 1, 1),
+	"cos":                NewFunction("cos", cosFunc, 1, 1),
+	"tan":                NewFunction("tan", tanFunc, 1, 1),
+	"asin":               NewFunction("asin", asinFunc, 1, 1),
+	"acos":               NewFunction("acos", acosFunc, 1, 1),
+	"atan":               NewFunction("atan", atanFunc, 1, 2),
+	"exact-integer-sqrt": NewFunction("exact-integer-sqrt", exactIntegerSqrtFunc, 1, 1),
+	"floor":              NewFunction("floor", roundFunc("floor"), 1, 1),
+	"ceiling":            NewFunction("ceiling", roundFunc("ceiling"), 1, 1),
+	"round":              NewFunction("round", roundFunc("round"), 1, 1),
+	"truncate":           NewFunction("truncate", roundFunc("truncate"), 1, 1),
+	"number->string":     NewFunction("number->string", numberToStringFunc, 1, 2),
+	"string->number":     NewFunction("string->number", stringToNumberFunc, 1, 2),
+
+	"char?":            NewFunction("char?", isCharFunc, 1, 1),
+	"char->integer":    NewFunction("char->integer", charToIntegerFunc, 1, 1),
+	"integer->char":    NewFunction("integer->char", integerToCharFunc, 1, 1),
+	"char-upcase":      NewFunction("char-upcase", charConverter(unicode.ToUpper), 1, 1),
+	"char-downcase":    NewFunction("char-downcase", charConverter(unicode.ToLower), 1, 1),
+	"char-foldcase":    NewFunction("char-foldcase", charConverter(foldRune), 1, 1),
+	"char-alphabetic?": NewFunction("char-alphabetic?", charPredicate(unicode.IsLetter), 1, 1),
+	"char-numeric?":    NewFunction("char-numeric?", charPredicate(unicode.IsDigit), 1, 1),
+	"char-whitespace?": NewFunction("char-whitespace?", charPredicate(unicode.IsSpace), 1, 1),
+	"char-upper-case?": NewFunction("char-upper-case?", charPredicate(unicode.IsUpper), 1, 1),
+	"char-lower-case?": NewFunction("char-lower-case?", charPredicate(unicode.IsLower), 1, 1),
+	"digit-value":      NewFunction("digit-value", digitValueFunc, 1, 1),
+	"char=?":           NewFunction("char=?", compareChars(false, func(a, b Char) bool { return a == b }), 1, -1),
+	"char<?":           NewFunction("char<?", compareChars(false, func(a, b Char) bool { return a < b }), 1, -1),
+	"char>?":           NewFunction("char>?", compareChars(false, func(a, b Char) bool { return a > b }), 1, -1),
+	"char<=?":          NewFunction("char<=?", compareChars(false, func(a, b Char) bool { return a <= b }), 1, -1),
+	"char>=?":          NewFunction("char>=?", compareChars(false, func(a, b Char) bool { return a >= b }), 1, -1),
+	"char-ci=?":        NewFunction("char-ci=?", compareChars(true, func(a, b Char) bool { return a == b }), 1, -1),
+	"char-ci<?":        NewFunction("char-ci<?", compareChars(true, func(a, b Char) bool { return a < b }), 1, -1),
+	"char-ci>?":        NewFunction("char-ci>?", compareChars(true, func(a, b Char) bool { return a > b }), 1, -1),
+	"char-ci<=?":       NewFunction("char-ci<=?", compareChars(true, func(a, b Char) bool { return a <= b }), 1, -1),
+	"char-ci>=?":       NewFunction("char-ci>=?", compareChars(true, func(a, b Char) bool { return a >= b }), 1, -1),
+
+	"make-string":           NewFunction("make-string", makeStringFunc, 1, 2),
+	"string":                NewFunction("string", stringFunc, 0, -1),
+	"string-length":         NewFunction("string-length", stringLengthFunc, 1, 1),
+	"string-ref":            NewFunction("string-ref", stringRefFunc, 2, 2),
+	"string-set!":           NewFunction("string-set!", stringSetFunc, 3, 3),
+	"string-fill!":          NewFunction("string-fill!", stringFillFunc, 2, 4),
+	"substring":             NewFunction("substring", substringFunc, 2, 3),
+	"string-append":         NewFunction("string-append", stringAppendFunc, 0, -1),
+	"string-copy":           NewFunction("string-copy", stringCopyFunc, 1, 3),
+	"string-upcase":         NewFunction("string-upcase", stringConverter(strings.ToUpper), 1, 1),
+	"string-downcase":       NewFunction("string-downcase", stringConverter(strings.ToLower), 1, 1),
+	"string-foldcase":       NewFunction("string-foldcase", stringConverter(foldString), 1, 1),
+	"string->list":          NewFunction("string->list", stringToListFunc, 1, 3),
+	"list->string":          NewFunction("list->string", listToStringFunc, 1, 1),
+	"string->symbol":        NewFunction("string->symbol", stringToSymbolFunc, 1, 1),
+	"symbol->string":        NewFunction("symbol->string", symbolToStringFunc, 1, 1),
+	"string-search-forward": NewFunction("string-search-forward", stringSearchForwardFunc, 2, 3),
+	"string-split":          NewFunction("string-split", stringSplitFunc, 1, 2),
+	"string-join":           NewFunction("string-join", stringJoinFunc, 1, 2),
+	"string-trim":           NewFunction("string-trim", stringConverter(trimSpace), 1, 1),
+	"string-trim-left":      NewFunction("string-trim-left", stringConverter(trimLeftSpace), 1, 1),
+	"string-trim-right":     NewFunction("string-trim-right", stringConverter(trimRightSpace), 1, 1),
+	"string=?":              NewFunction("string=?", compareStrings(false, func(c int) bool { return c == 0 }), 1, -1),
+	"string<?":              NewFunction("string<?", compareStrings(false, func(c int) bool { return c < 0 }), 1, -1),
+	"string>?":              NewFunction("string>?", compareStrings(false, func(c int) bool { return c > 0 }), 1, -1),
+	"string<=?":             NewFunction("string<=?", compareStrings(false, func(c int) bool { return c <= 0 }), 1, -1),
+	"string>=?":             NewFunction("string>=?", compareStrings(false, func(c int) bool { return c >= 0 }), 1, -1),
+	"string-ci=?":           NewFunction("string-ci=?", compareStrings(true, func(c int) bool { return c == 0 }), 1, -1),
+	"string-ci<?":           NewFunction("string-ci<?", compareStrings(true, func(c int) bool { return c < 0 }), 1, -1),
+	"string-ci>?":           NewFunction("string-ci>?", compareStrings(true, func(c int) bool { return c > 0 }), 1, -1),
+	"string-ci<=?":          NewFunction("string-ci<=?", compareStrings(true, func(c int) bool { return c <= 0 }), 1, -1),
+	"string-ci>=?":          NewFunction("string-ci>=?", compareStrings(true, func(c int) bool { return c >= 0 }), 1, -1),
+
+	"vector?":       NewFunction("vector?", isVectorFunc, 1, 1),
+	"make-vector":   NewFunction("make-vector", makeVectorFunc, 1, 2),
+	"vector":        NewFunction("vector", vectorFunc, -1, -1),
+	"vector-length": NewFunction("vector-length", vectorLengthFunc, 1, 1),
+	"vector-ref":    NewFunction("vector-ref", vectorRefFunc, 2, 2),
+	"vector-set!":   NewFunction("vector-set!", vectorSetFunc, 3, 3),
+	"vector->list":  NewFunction("vector->list", vectorToListFunc, 1, 3),
+	"list->vector":  NewFunction("list->vector", listToVectorFunc, 1, 1),
+	"vector-fill!":  NewFunction("vector-fill!", vectorFillFunc, 2, 4),
+	"vector-copy":   NewFunction("vector-copy", vectorCopyFunc, 1, 3),
+	"vector-append": NewFunction("vector-append", vectorAppendFunc, -1, -1),
+
+	"bytevector?":         NewFunction("bytevector?", isBytevectorFunc, 1, 1),
+	"make-bytevector":     NewFunction("make-bytevector", makeBytevectorFunc, 1, 2),
+	"bytevector":          NewFunction("bytevector", bytevectorFunc, -1, -1),
+	"bytevector-length":   NewFunction("bytevector-length", bytevectorLengthFunc, 1, 1),
+	"bytevector-u8-ref":   NewFunction("bytevector-u8-ref", bytevectorU8RefFunc, 2, 2),
+	"bytevector-u8-set!":  NewFunction("bytevector-u8-set!", bytevectorU8SetFunc, 3, 3),
+	"bytevector-copy":     NewFunction("bytevector-copy", bytevectorCopyFunc, 1, 3),
+	"bytevector-copy!":    NewFunction("bytevector-copy!", bytevectorCopyToFunc, 3, 5),
+	"bytevector-append":   NewFunction("bytevector-append", bytevectorAppendFunc, -1, -1),
+	"utf8->string":        NewFunction("utf8->string", utf8ToStringFunc, 1, 3),
+	"string->utf8":        NewFunction("string->utf8", stringToUtf8Func, 1, 3),
+	"native-endianness":   NewFunction("native-endianness", nativeEndiannessFunc, 0, 0),
+	"bytevector-u16-ref":  NewFunction("bytevector-u16-ref", bytevectorIntRef("bytevector-u16-ref", 2, false), 3, 3),
+	"bytevector-s16-ref":  NewFunction("bytevector-s16-ref", bytevectorIntRef("bytevector-s16-ref", 2, true), 3, 3),
+	"bytevector-u32-ref":  NewFunction("bytevector-u32-ref", bytevectorIntRef("bytevector-u32-ref", 4, false), 3, 3),
+	"bytevector-s32-ref":  NewFunction("bytevector-s32-ref", bytevectorIntRef("bytevector-s32-ref", 4, true), 3, 3),
+	"bytevector-u64-ref":  NewFunction("bytevector-u64-ref", bytevectorIntRef("bytevector-u64-ref", 8, false), 3, 3),
+	"bytevector-s64-ref":  NewFunction("bytevector-s64-ref", bytevectorIntRef("bytevector-s64-ref", 8, true), 3, 3),
+	"bytevector-u16-set!": NewFunction("bytevector-u16-set!", bytevectorIntSet("bytevector-u16-set!", 2, false), 4, 4),
+	"bytevector-s16-set!": NewFunction("bytevector-s16-set!", bytevectorIntSet("bytevector-s16-set!", 2, true), 4, 4),
+	"bytevector-u32-set!": NewFunction("bytevector-u32-set!", bytevectorIntSet("bytevector-u32-set!", 4, false), 4, 4),
+	"bytevector-s32-set!": NewFunction("bytevector-s32-set!", bytevectorIntSet("bytevector-s32-set!", 4, true), 4, 4),
+	"bytevector-u64-set!": NewFunction("bytevector-u64-set!", bytevectorIntSet("bytevector-u64-set!", 8, false), 4, 4),
+	"bytevector-s64-set!": NewFunction("bytevector-s64-set!", bytevectorIntSet("bytevector-s64-set!", 8, true), 4, 4),
+
+	"hash-table?":            NewFunction("hash-table?", isHashTableFunc, 1, 1),
+	"make-hash-table":        NewFunction("make-hash-table", makeHashTableFunc, 0, 1),
+	"hash-table-ref/default": NewFunction("hash-table-ref/default", hashTableRefDefaultFunc, 3, 3),
+	"hash-table-set!":        NewFunction("hash-table-set!", hashTableSetFunc, 3, 3),
+	"hash-table-delete!":     NewFunction("hash-table-delete!", hashTableDeleteFunc, 2, 2),
+	"hash-table-contains?":   NewFunction("hash-table-contains?", hashTableContainsFunc, 2, 2),
+	"hash-table-exists?":     NewFunction("hash-table-exists?", hashTableContainsFunc, 2, 2),
+	"hash-table-size":        NewFunction("hash-table-size", hashTableSizeFunc, 1, 1),
+	"hash-table-keys":        NewFunction("hash-table-keys", hashTableLister(func(e *hashEntry) Expression { return e.key }), 1, 1),
+	"hash-table-values":      NewFunction("hash-table-values", hashTableLister(func(e *hashEntry) Expression { return e.value }), 1, 1),
+	"hash-table->alist":      NewFunction("hash-table->alist", hashTableLister(func(e *hashEntry) Expression { return &Pair{e.key, e.value} }), 1, 1),
+
+	"eq?":    NewFunction("eq?", isEqFunc, 2, 2),
+	"eqv?":   NewFunction("eqv?", isEqvFunc, 2, 2),
+	"equal?": NewFunction("equal?", isEqualFunc, 2, 2),
+
+	"raise":                  NewFunction("raise", raiseFunc, 1, 1),
+	"error":                  NewFunction("error", errorFunc, 1, -1),
+	"error-object?":          NewFunction("error-object?", isErrorObjectFunc, 1, 1),
+	"error-object-message":   NewFunction("error-object-message", errorObjectMessageFunc, 1, 1),
+	"error-object-irritants": NewFunction("error-object-irritants", errorObjectIrritantsFunc, 1, 1),
+
+	"values": NewFunction("values", valuesFunc, 0, -1),
+
+	"symbol?":                    NewFunction("symbol?", isSymbolFunc, 1, 1),
+	"symbol<?":                   NewFunction("symbol<?", symbolLessFunc, 1, -1),
+	"gensym":                     NewFunction("gensym", gensymFunc, 0, 1),
+	"generate-uninterned-symbol": NewFunction("generate-uninterned-symbol", gensymFunc, 0, 1),
+
+	"read":        NewFunction("read", readFunc, 0, 0),
+	"write":       NewFunction("write", writeFunc, 1, 1),
+	"eof-object":  NewFunction("eof-object", eofObjectFunc, 0, 0),
+	"eof-object?": NewFunction("eof-object?", isEOFObjectFunc, 1, 1),
+}
+
+// controlFunctions are the builtin procedures accessing the continuation of the evaluation.
+var controlFunctions = map[Symbol]ControlFunction{
+	"call-with-current-continuation": NewControlFunction("call-with-current-continuation", callCC, 1, 1),
+	"call/cc":                        NewControlFunction("call/cc", callCC, 1, 1),
+	"call-with-escape-continuation":  NewControlFunction("call-with-escape-continuation", callEC, 1, 1),
+	"call/ec":                        NewControlFunction("call/ec", callEC, 1, 1),
+	"dynamic-wind":                   NewControlFunction("dynamic-wind", dynamicWind, 3, 3),
+	"raise-continuable":              NewControlFunction("raise-continuable", raiseContinuable, 1, 1),
+	"with-exception-handler":         NewControlFunction("with-exception-handler", withExceptionHandler, 2, 2),
+	"call-with-values":               NewControlFunction("call-with-values", callWithValues, 2, 2),
+	"force":                          controlForce,
+
+	"hash-table-ref":             NewControlFunction("hash-table-ref", hashTableRef, 2, 3),
+	"hash-table-walk":            NewControlFunction("hash-table-walk", hashTableWalk, 2, 2),
+	"hash-table-update!":         NewControlFunction("hash-table-update!", hashTableUpdate, 3, 4),
+	"hash-table-update!/default": NewControlFunction("hash-table-update!/default", hashTableUpdateDefault, 4, 4),
+
+	"memq":   NewControlFunction("memq", memberFunction("memq", isEq), 2, 2),
+	"memv":   NewControlFunction("memv", memberFunction("memv", isEqv), 2, 2),
+	"member": NewControlFunction("member", memberFunction("member", isEqual), 2, 3),
+	"assq":   NewControlFunction("assq", assocFunction("assq", isEq), 2, 2),
+	"assv":   NewControlFunction("assv", assocFunction("assv", isEqv), 2, 2),
+	"assoc":  NewControlFunction("assoc", assocFunction("assoc", isEqual), 2, 3),
+
+	"string-index": NewControlFunction("string-index", stringIndex, 2, 2),
+
+	"vector-map":      NewControlFunction("vector-map", vectorMap, 2, -1),
+	"vector-for-each": NewControlFunction("vector-for-each", vectorForEach, 2, -1),
+
+	"macroexpand":   NewControlFunction("macroexpand", macroexpander(true), 1, 1),
+	"macroexpand-1": NewControlFunction("macroexpand-1", macroexpander(false), 1, 1),
 }
 
 func setCarImpl(args ...Expression) (Expression, error) {
@@ -391,6 +1192,9 @@ func setupBuiltinEnv() *Env {
 	for k, fn := range builtinFunctions {
 		builtinEnv.Set(k, fn)
 	}
+	for k, fn := range controlFunctions {
+		builtinEnv.Set(k, fn)
+	}
 	loadBuiltinProcedures(builtinEnv)
 	return builtinEnv
 }
@@ -416,11 +1220,6 @@ const builtinProcedures = `
       0
       (proc (car items) (reduce proc (cdr items)))))
 
-(define (remainder a b)
-  (if (< a b)
-      a
-      (remainder (- a b) b)))
-
 (define list-ref
     (lambda (lst place)
       (if (null? lst)
@@ -437,6 +1236,16 @@ const builtinProcedures = `
 (define (list-length lst)
 	(if (null? lst) 0 (+ (list-length (cdr lst)) 1)))
 
+(define (string-map proc s)
+	(list->string (map proc (string->list s))))
+
+(define (string-for-each proc s)
+	(define (iter chars)
+		(if (null? chars)
+			#t
+			(begin (proc (car chars)) (iter (cdr chars)))))
+	(iter (string->list s)))
+
 `
 
 func loadBuiltinProcedures(env *Env) {
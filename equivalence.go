@@ -0,0 +1,225 @@
+package goscheme
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// isEq compares the values by identity, the values of comparable go types such as symbols, small numbers, characters
+// and booleans are compared by value.
+func isEq(a, b Expression) bool {
+	if IsNullExp(a) || IsNullExp(b) {
+		return IsNullExp(a) && IsNullExp(b)
+	}
+	switch v1 := a.(type) {
+	case Function:
+		v2, ok := b.(Function)
+		return ok && isSameFunction(v1, v2)
+	case ControlFunction:
+		v2, ok := b.(ControlFunction)
+		return ok && isSameFunction(v1.Function, v2.Function) &&
+			reflect.ValueOf(v1.control).Pointer() == reflect.ValueOf(v2.control).Pointer()
+	}
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// isSameFunction checks whether the two Function values wrap the same go function with the same name. Function holds
+// a go func which is not comparable with ==.
+func isSameFunction(f1, f2 Function) bool {
+	return f1.name == f2.name && reflect.ValueOf(f1.function).Pointer() == reflect.ValueOf(f2.function).Pointer()
+}
+
+// isEqv compares numbers by exactness and value, and the other values by identity like isEq. The inexact reals are
+// compared by the bit patterns, so 0.0 and -0.0 differ.
+func isEqv(a, b Expression) bool {
+	switch v1 := a.(type) {
+	case Real:
+		if v2, ok := b.(Real); ok {
+			return sameFloat(float64(v1), float64(v2))
+		}
+	case Complex:
+		if v2, ok := b.(Complex); ok {
+			return sameFloat(real(v1), real(v2)) && sameFloat(imag(v1), imag(v2))
+		}
+	}
+	if v1, ok := a.(Number); ok {
+		v2, ok := b.(Number)
+		return ok && v1.IsExact() == v2.IsExact() && numEqual(v1, v2)
+	}
+	return isEq(a, b)
+}
+
+// sameFloat checks whether the floats have the same bit pattern.
+func sameFloat(a, b float64) bool {
+	return math.Float64bits(a) == math.Float64bits(b)
+}
+
+// isEqual compares the values structurally, pairs, vectors, strings, bytevectors and records are equal when their
+// contents are equal, the other values are compared with isEqv. Circular structures are compared without looping
+// forever.
+func isEqual(a, b Expression) bool {
+	return (&equalComparison{visited: make(map[[2]Expression]bool)}).equal(a, b)
+}
+
+// equalComparison keeps the pairs of compound values already under comparison. Meeting such a pair again means the
+// structures are circular at the same place, so the pair is considered equal and the rest of the comparison decides.
+type equalComparison struct {
+	visited map[[2]Expression]bool
+}
+
+// enter records the pair of compound values and reports whether it was already visited.
+func (c *equalComparison) enter(a, b Expression) bool {
+	key := [2]Expression{a, b}
+	if c.visited[key] {
+		return true
+	}
+	c.visited[key] = true
+	return false
+}
+
+func (c *equalComparison) equal(a, b Expression) bool {
+	for {
+		if IsNullExp(a) || IsNullExp(b) {
+			return IsNullExp(a) && IsNullExp(b)
+		}
+		if s1, ok := stringValue(a); ok {
+			s2, ok := stringValue(b)
+			return ok && s1 == s2
+		}
+		v1, ok := a.(*Pair)
+		if !ok {
+			return c.equalAtom(a, b)
+		}
+		v2, ok := b.(*Pair)
+		if !ok {
+			return false
+		}
+		if c.enter(v1, v2) {
+			return true
+		}
+		if !c.equal(v1.Car, v2.Car) {
+			return false
+		}
+		// walk the cdr in the loop so long lists don't grow the go stack
+		a, b = v1.Cdr, v2.Cdr
+	}
+}
+
+// equalAtom compares the values which are not pairs.
+func (c *equalComparison) equalAtom(a, b Expression) bool {
+	switch v1 := a.(type) {
+	case *Vector:
+		v2, ok := b.(*Vector)
+		if !ok || len(v1.Elements) != len(v2.Elements) {
+			return false
+		}
+		if c.enter(v1, v2) {
+			return true
+		}
+		for i := range v1.Elements {
+			if !c.equal(v1.Elements[i], v2.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *Bytevector:
+		v2, ok := b.(*Bytevector)
+		return ok && bytes.Equal(v1.Bytes, v2.Bytes)
+	case *Record:
+		v2, ok := b.(*Record)
+		if !ok || v1.recordType != v2.recordType {
+			return false
+		}
+		if c.enter(v1, v2) {
+			return true
+		}
+		for i := range v1.values {
+			if !c.equal(v1.values[i], v2.values[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return isEqv(a, b)
+}
+
+func isEqFunc(args ...Expression) (Expression, error) {
+	return isEq(args[0], args[1]), nil
+}
+
+func isEqvFunc(args ...Expression) (Expression, error) {
+	return isEqv(args[0], args[1]), nil
+}
+
+func isEqualFunc(args ...Expression) (Expression, error) {
+	return isEqual(args[0], args[1]), nil
+}
+
+// memberFunction returns the procedure which searches the list for the first element equivalent to the object and
+// returns the sublist starting with it, or #f.
+// (memv 2 '(1 2 3)) => (2 3)
+func memberFunction(name string, predicate func(a, b Expression) bool) controlFunc {
+	return func(m *machine, args ...Expression) error {
+		return searchList(m, name, args, predicate, func(p *Pair) (Expression, Expression, error) {
+			return p.Car, p, nil
+		})
+	}
+}
+
+// assocFunction returns the procedure which searches the association list for the first pair whose car is equivalent
+// to the key, or #f.
+// (assq 'b '((a 1) (b 2))) => (b 2)
+func assocFunction(name string, predicate func(a, b Expression) bool) controlFunc {
+	return func(m *machine, args ...Expression) error {
+		return searchList(m, name, args, predicate, func(p *Pair) (Expression, Expression, error) {
+			entry, ok := p.Car.(*Pair)
+			if !ok || entry.IsNull() {
+				return nil, nil, fmt.Errorf("%s: %s is not a pair", name, valueToString(p.Car))
+			}
+			return entry.Car, entry, nil
+		})
+	}
+}
+
+// searchList walks the list args[1] for the first element whose key is equivalent to the object args[0] and returns
+// its result, or #f when there is none. The keys are compared by the optional procedure args[2] on the machine, or by
+// the predicate.
+func searchList(m *machine, name string, args []Expression, predicate func(a, b Expression) bool,
+	element func(p *Pair) (key, result Expression, err error)) error {
+	var next func(m *machine, list Expression) error
+	next = func(m *machine, list Expression) error {
+		for !IsNullExp(list) {
+			p, ok := list.(*Pair)
+			if !ok {
+				return fmt.Errorf("%s: %s is not a list", name, valueToString(args[1]))
+			}
+			key, result, err := element(p)
+			if err != nil {
+				return err
+			}
+			if len(args) > 2 {
+				m.push(func(m *machine, same Expression) error {
+					if IsTrue(same) {
+						m.ret(result)
+						return nil
+					}
+					return next(m, p.Cdr)
+				})
+				return m.apply(args[2], []Expression{args[0], key})
+			}
+			if predicate(args[0], key) {
+				m.ret(result)
+				return nil
+			}
+			list = p.Cdr
+		}
+		m.ret(false)
+		return nil
+	}
+	return next(m, args[1])
+}
@@ -0,0 +1,71 @@
+package goscheme
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestIsEqual(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"(equal? '(1 (2 #(3))) (list 1 (list 2 (vector 3))))", "#t"},
+		{"(equal? '(1 2) '(1 2 3))", "#f"},
+		{`(equal? "ab" (string-copy "ab"))`, "#t"},
+		{"(equal? 2 2.0)", "#f"},
+		{"(equal? 1/2 (/ 2 4))", "#t"},
+		{"(equal? #u8(1 2) (bytevector 1 2))", "#t"},
+		{"(equal? 'a 'a)", "#t"},
+		{"(equal? '() (list))", "#t"},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+}
+
+func TestEquivalence(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"(list (eq? 'a 'a) (eq? '() '()) (eq? car car) (eq? #\\a #\\a) (eq? (list 1) (list 1)))", "(#t #t #t #t #f)"},
+		{"(let ((p (cons 1 2))) (list (eq? p p) (eqv? p p) (eq? p (cons 1 2))))", "(#t #t #f)"},
+		{"(list (eqv? 2 2) (eqv? 2 2.0) (eqv? 100000000000000000000 100000000000000000000) (eqv? 1/2 2/4))", "(#t #f #t #t)"},
+		{"(list (eqv? 0.0 -0.0) (eqv? 0.0 0.0) (eqv? 1.5 1.5) (equal? 0.0 -0.0) (= 0.0 -0.0) (memv -0.0 '(0.0 -0.0)))",
+			"(#f #t #t #f #t (-0.0))"},
+		{"(list (eqv? (vector) (vector 1)) (eqv? (lambda () 1) (lambda () 2)) (eqv? #f '()))", "(#f #f #f)"},
+		{"(list (equal? (vector 1 2) (vector 1 2)) (eqv? (vector 1 2) (vector 1 2)))", "(#t #f)"},
+		{"(define-record-type point (make-point x y) point? (x point-x) (y point-y)) (equal? (make-point 1 '(2)) (make-point 1 (list 2)))", "#t"},
+		{"(define a (list 1 2)) (set-cdr! (cdr a) a) (define b (list 1 2)) (set-cdr! (cdr b) b) (equal? a b)", "#t"},
+		{"(define c (list 1 2 1 2)) (set-cdr! (cdr (cdr (cdr c))) c) (equal? a c)", "#t"},
+		{"(define d (list 1 3)) (set-cdr! (cdr d) d) (equal? a d)", "#f"},
+		{"(define v (vector 1 0)) (vector-set! v 1 v) (define w (vector 1 0)) (vector-set! w 1 w) (equal? v w)", "#t"},
+		{"(list (= 1 1.0) (= 'a 'a) (= (list 1) (list 1)))", "(#t #t #f)"},
+		{"(memq 'c '(a b c d))", "(c d)"},
+		{"(memq 'e '(a b c d))", "#f"},
+		{"(memv 101 '(100 101 102))", "(101 102)"},
+		{"(list (memq (list 'a) '(b (a) c)) (member (list 'a) '(b (a) c)))", "(#f ((a) c))"},
+		{`(member "B" '("a" "b" "c") (lambda (a b) (string=? (string-downcase a) b)))`, `("b" "c")`},
+		{"(member 2.0 '(1 2 3) =)", "(2 3)"},
+		{"(assq 'b '((a 1) (b 2)))", "(b 2)"},
+		{"(assq 'd '((a 1) (b 2)))", "#f"},
+		{"(assv 5 '((2 3) (5 7) (11 13)))", "(5 7)"},
+		{"(list (assq (list 'a) '(((a)) ((b)))) (assoc (list 'a) '(((a)) ((b)))))", "(#f ((a)))"},
+		{"(assoc 2.0 '((1 1) (2 4) (3 9)) =)", "(2 4)"},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+
+	for _, input := range []string{"(memq 'a 'a)", "(assq 'a '(a))", "(assv 1 '((0 . 1) . 2))", "(member 1 '(1) car)"} {
+		_, err := EvalAll(strToToken(input), env)
+		assert.NotNil(t, err, input)
+	}
+}
@@ -5,285 +5,210 @@ import (
 	"fmt"
 	"os"
 	"path"
-	"regexp"
-	"strconv"
 )
 
 // Eval is the main function to evaluate the expression in an environment.
-func Eval(exp Expression, env *Env) (ret Expression, err error) {
-	for {
-		if IsPrimitiveExpression(exp) {
-			return evalPrimitive(exp)
-		}
-		if IsSymbol(exp) {
-			s, _ := exp.(string)
-			ret, err = env.Find(Symbol(s))
-			return
-		}
-		if IsSyntaxExpression(exp) {
-			syntaxName, args, err := retrieveSyntaxAndArgs(exp)
-			if err != nil {
-				return UndefObj, err
-			}
-			syntax := SyntaxMap[syntaxName]
-			exp, err = applySyntaxExpression(syntax, args, env)
-			if err != nil {
-				return UndefObj, err
-			}
-		} else {
-			ops, ok := exp.([]Expression)
-			if !ok {
-				return UndefObj, fmt.Errorf("%s is not a valid expression", exp)
-			}
-			nextExp, newEnv, err := applyCallable(ops[0], ops[1:], env)
-			if err != nil {
-				return UndefObj, err
-			}
-			exp = nextExp
-			env = newEnv
-		}
-	}
-}
-
-// for tail recursion optimization, return the next expression will be executed and the new environment to execute the
-// next loop in eval
-func applyCallable(process Expression, argExpressions []Expression, env *Env) (Expression, *Env, error) {
-	fn, err := Eval(process, env)
+func Eval(exp Expression, env *Env) (Expression, error) {
+	exp, err := readExpression(exp)
 	if err != nil {
-		return fn, env, err
-	}
-	switch p := fn.(type) {
-	case Function:
-		var args []Expression
-		for _, arg := range argExpressions {
-			v, err := Eval(arg, env)
-			if err != nil {
-				return UndefObj, env, err
-			}
-			args = append(args, v)
-		}
-		ret, err := p.Call(args...)
-		return ret, env, err
-	case *LambdaProcess:
-		newEnv := &Env{outer: p.env, frame: make(map[Symbol]Expression)}
-		if len(argExpressions) != len(p.params) {
-			return UndefObj, env, errors.New(fmt.Sprintf("%v\n", p.String()) + "require " + strconv.Itoa(len(p.params)) + " but " + strconv.Itoa(len(argExpressions)) + " provide")
-		}
-		for i, arg := range argExpressions {
-			val, err := Eval(arg, env)
-			if err != nil {
-				return UndefObj, env, err
-			}
-			newEnv.Set(p.params[i], val)
-		}
-		return p.Body(), newEnv, nil
-	default:
-		return UndefObj, env, fmt.Errorf("%v is not callable", fn)
+		return UndefObj, err
 	}
+	m := &machine{}
+	m.eval(exp, env)
+	return m.run()
 }
 
-func applySyntaxExpression(syntax *Syntax, args []Expression, env *Env) (Expression, error) {
-	return syntax.Eval(args, env)
+// applyProcedure calls the procedure value with the evaluated arguments on a new machine, used by the go code outside
+// of the evaluation. The builtin procedures calling back into scheme are ControlFunctions applying the procedures on
+// the running machine instead.
+func applyProcedure(procedure Expression, args ...Expression) (Expression, error) {
+	m := &machine{}
+	m.start(procedure, args)
+	return m.run()
 }
 
-func retrieveSyntaxAndArgs(exp Expression) (syntaxName string, args []Expression, err error) {
-	pieces, ok := exp.([]Expression)
-	if !ok || len(pieces) < 1 {
-		err = fmt.Errorf("%s is not a valid syntax expression", exp)
-		return
-	}
-	syntaxName, _ = pieces[0].(string)
-	args = pieces[1:]
-	return
-}
-func evalPrimitive(exp Expression) (Expression, error) {
-	if IsNullExp(exp) {
-		return NilObj, nil
-	}
-	if IsUndefObj(exp) {
-		return UndefObj, nil
-	}
-	if IsNumber(exp) {
-		return expressionToNumber(exp)
-	}
-	if IsBoolean(exp) {
-		return IsTrue(exp), nil
+func evalSet(m *machine, args []Expression, env *Env) error {
+	if len(args) != 2 {
+		return errors.New("set!: syntax error (set! requires variable and value arguments)")
 	}
-	if IsString(exp) {
-		return expToString(exp)
+	if _, err := transExpressionToSymbol(args[0]); err != nil {
+		return err
 	}
-	return exp, nil
+	m.evalThen(args[1], env, func(m *machine, val Expression) error {
+		m.ret(UndefObj)
+		return assignVariable(args[0], val, env)
+	})
+	return nil
 }
 
-func evalSet(args []Expression, env *Env) (Expression, error) {
-	if len(args) != 2 {
-		return UndefObj, errors.New("set!: syntax error (set! requires variable and value arguments)")
-	}
-	sym, err := transExpressionToSymbol(args[0])
+// assignVariable sets the value of the variable already defined in env or its outer environments.
+func assignVariable(variable Expression, val Expression, env *Env) error {
+	sym, err := transExpressionToSymbol(variable)
 	if err != nil {
-		return UndefObj, err
-	}
-	var val Expression
-	val, err = Eval(args[1], env)
-	if err != nil {
-		return UndefObj, err
+		return err
 	}
 	currentEnv := env
 	for currentEnv != nil {
-		if _, ok := currentEnv.frame[sym]; ok {
+		if current, ok := currentEnv.frame[sym]; ok {
+			if _, err := assigned(sym, current); err != nil {
+				return fmt.Errorf("set!: %s", err)
+			}
 			currentEnv.Set(sym, val)
-			return UndefObj, nil
+			return nil
 		}
-		currentEnv = env.outer
+		currentEnv = currentEnv.outer
 	}
-	return UndefObj, fmt.Errorf("variable %v cannot set! before define", sym)
+	if id, ok := variable.(*identifier); ok {
+		// the identifier inserted by macro refers to the variable where the macro is defined
+		return assignVariable(id.name, val, id.env)
+	}
+	return fmt.Errorf("variable %v cannot set! before define", sym)
 }
 
-func evalLetRec(args []Expression, env *Env) (Expression, error) {
-	if len(args) < 2 {
-		return UndefObj, errors.New("letrec: syntax error (letrec should pass the variables and body)")
-	}
-	bindings, ok := args[0].([]Expression)
-	if !ok {
-		return UndefObj, errors.New("letrec: syntax error (not a valid binding)")
+func evalLetRec(m *machine, args []Expression, env *Env) error {
+	symbols, inits, err := letBindings("letrec", args)
+	if err != nil {
+		return err
 	}
 	newEnv := &Env{outer: env, frame: make(map[Symbol]Expression)}
-	// init symbols with undef
-	for _, exp := range bindings {
-		binding, ok := exp.([]Expression)
-		if !ok || len(binding) != 2 {
-			return UndefObj, errors.New("letrec: syntax error (not a valid binding)")
-		}
-		sym, err := transExpressionToSymbol(binding[0])
-		if err != nil {
-			return UndefObj, err
-		}
-		newEnv.Set(sym, UndefObj)
+	// the symbols are unassigned until their values are set
+	for _, sym := range symbols {
+		newEnv.Set(sym, unassignedObj)
 	}
 	// set value for symbols
-	for _, exp := range bindings {
-		binding, _ := exp.([]Expression)
-		sym, _ := transExpressionToSymbol(binding[0])
-		val, err := Eval(binding[1], newEnv)
-		if err != nil {
-			return UndefObj, err
+	var bind func(m *machine, i int) error
+	bind = func(m *machine, i int) error {
+		if i == len(symbols) {
+			m.evalBody(args[1:], newEnv)
+			return nil
 		}
-		newEnv.Set(sym, val)
+		m.evalThen(inits[i], newEnv, func(m *machine, val Expression) error {
+			newEnv.Set(symbols[i], val)
+			return bind(m, i+1)
+		})
+		return nil
 	}
-	var ret Expression
-	var err error
-	for _, exp := range args[1:] {
-		ret, err = Eval(exp, newEnv)
-		if err != nil {
-			return ret, err
-		}
-	}
-	return ret, nil
+	return bind(m, 0)
 }
 
-func evalL2RLet(args []Expression, env *Env) (Expression, error) {
+// letBindings returns the variables and the init expressions of the bindings of the let forms.
+func letBindings(name string, args []Expression) ([]Symbol, []Expression, error) {
 	if len(args) < 2 {
-		return UndefObj, errors.New("let*: syntax error (let* should pass the variables and body)")
+		return nil, nil, fmt.Errorf("%s: syntax error (%s should pass the variables and body)", name, name)
 	}
 	bindings, ok := args[0].([]Expression)
 	if !ok {
-		return UndefObj, errors.New("let*: syntax error (not a valid binding)")
+		return nil, nil, fmt.Errorf("%s: syntax error (not a valid binding)", name)
 	}
-	var outerEnv, currentEnv *Env
-	outerEnv = env
+	var symbols []Symbol
+	var inits []Expression
 	for _, exp := range bindings {
-		currentEnv = &Env{outer: outerEnv, frame: make(map[Symbol]Expression)}
 		binding, ok := exp.([]Expression)
 		if !ok || len(binding) != 2 {
-			return UndefObj, errors.New("let*: syntax error (not a valid binding)")
+			return nil, nil, fmt.Errorf("%s: syntax error (not a valid binding)", name)
 		}
 		sym, err := transExpressionToSymbol(binding[0])
 		if err != nil {
-			return UndefObj, err
-		}
-		val, err := Eval(binding[1], currentEnv)
-		if err != nil {
-			return UndefObj, nil
+			return nil, nil, err
 		}
-		currentEnv.Set(sym, val)
-		outerEnv = currentEnv
+		symbols = append(symbols, sym)
+		inits = append(inits, binding[1])
 	}
-	var ret Expression
-	var err error
-	for _, exp := range args[1:] {
-		ret, err = Eval(exp, currentEnv)
-		if err != nil {
-			return ret, err
+	return symbols, inits, nil
+}
+
+func evalL2RLet(m *machine, args []Expression, env *Env) error {
+	symbols, inits, err := letBindings("let*", args)
+	if err != nil {
+		return err
+	}
+	var bind func(m *machine, i int, currentEnv *Env) error
+	bind = func(m *machine, i int, currentEnv *Env) error {
+		if i == len(symbols) {
+			m.evalBody(args[1:], &Env{outer: currentEnv, frame: make(map[Symbol]Expression)})
+			return nil
 		}
+		m.evalThen(inits[i], currentEnv, func(m *machine, val Expression) error {
+			newEnv := &Env{outer: currentEnv, frame: make(map[Symbol]Expression)}
+			newEnv.Set(symbols[i], val)
+			return bind(m, i+1, newEnv)
+		})
+		return nil
 	}
-	return ret, nil
+	return bind(m, 0, env)
 }
 
-func evalLet(args []Expression, env *Env) (Expression, error) {
-	if len(args) < 2 {
-		return UndefObj, errors.New("let: syntax error (let should pass the variables and body)")
+func evalLet(m *machine, args []Expression, env *Env) error {
+	if len(args) > 0 && IsSymbol(args[0]) {
+		return evalNamedLet(m, args, env)
 	}
-	bindings, ok := args[0].([]Expression)
-	if !ok {
-		return UndefObj, errors.New("let: syntax error (not a valid binding)")
+	symbols, inits, err := letBindings("let", args)
+	if err != nil {
+		return err
 	}
-	newEnv := &Env{outer: env, frame: make(map[Symbol]Expression)}
-	for _, exp := range bindings {
-		binding, ok := exp.([]Expression)
-		if !ok || len(binding) != 2 {
-			return UndefObj, errors.New("let: syntax error (not a valid binding)")
+	return m.evalArgs(inits, env, func(m *machine, values []Expression) error {
+		newEnv := &Env{outer: env, frame: make(map[Symbol]Expression)}
+		for i, sym := range symbols {
+			newEnv.Set(sym, values[i])
 		}
-		sym, err := transExpressionToSymbol(binding[0])
-		if err != nil {
-			return UndefObj, err
-		}
-		val, err := Eval(binding[1], env)
-		if err != nil {
-			return UndefObj, nil
-		}
-		newEnv.Set(sym, val)
+		m.evalBody(args[1:], newEnv)
+		return nil
+	})
+}
+
+// evalNamedLet binds the name to the procedure of the body in the scope of the body and calls it with the inits,
+// (let loop ((i 0)) (if (< i 10) (loop (+ i 1)) i)).
+func evalNamedLet(m *machine, args []Expression, env *Env) error {
+	name, err := transExpressionToSymbol(args[0])
+	if err != nil {
+		return err
 	}
-	var ret Expression
-	var err error
-	for _, exp := range args[1:] {
-		ret, err = Eval(exp, newEnv)
-		if err != nil {
-			return ret, err
-		}
+	symbols, inits, err := letBindings("let", args[1:])
+	if err != nil {
+		return err
 	}
-	return ret, nil
+	return m.evalArgs(inits, env, func(m *machine, values []Expression) error {
+		loopEnv := &Env{outer: env, frame: make(map[Symbol]Expression)}
+		p := makeLambdaProcess(formals{params: symbols}, args[2:], loopEnv)
+		loopEnv.Set(name, p)
+		return m.apply(p, values)
+	})
 }
 
-func evalAnd(args []Expression, env *Env) (Expression, error) {
+func evalAnd(m *machine, args []Expression, env *Env) error {
 	if len(args) < 1 {
-		return UndefObj, errors.New("and require at least 1 argument")
+		return errors.New("and require at least 1 argument")
 	}
-	for _, e := range args {
-		val, err := Eval(e, env)
-		if err != nil {
-			return UndefObj, err
-		}
+	m.evalThen(args[0], env, func(m *machine, val Expression) error {
 		if !IsTrue(val) {
-			return false, nil
+			m.ret(false)
+			return nil
 		}
-	}
-	return true, nil
+		if len(args) == 1 {
+			m.ret(true)
+			return nil
+		}
+		return evalAnd(m, args[1:], env)
+	})
+	return nil
 }
 
-func evalOr(args []Expression, env *Env) (Expression, error) {
+func evalOr(m *machine, args []Expression, env *Env) error {
 	if len(args) < 1 {
-		return UndefObj, errors.New("or require at least 1 argument")
+		return errors.New("or require at least 1 argument")
 	}
-	for _, e := range args {
-		result, err := Eval(e, env)
-		if err != nil {
-			return UndefObj, err
-		}
+	m.evalThen(args[0], env, func(m *machine, result Expression) error {
 		if IsTrue(result) {
-			return true, nil
+			m.ret(true)
+			return nil
 		}
-	}
-	return false, nil
+		if len(args) == 1 {
+			m.ret(false)
+			return nil
+		}
+		return evalOr(m, args[1:], env)
+	})
+	return nil
 }
 
 func evalDelay(args []Expression, env *Env) (Expression, error) {
@@ -293,127 +218,111 @@ func evalDelay(args []Expression, env *Env) (Expression, error) {
 	return NewThunk(args[0], env), nil
 }
 
-func expToString(exp Expression) (String, error) {
-	switch s := exp.(type) {
-	case string:
-		pattern := regexp.MustCompile(`"((.|[\r\n])*?)"`)
-		m := pattern.FindAllStringSubmatch(s, -1)
-		if len(m) < 1 || len(m[0]) < 2 {
-			return "", errors.New("not a string, format invalid")
-		}
-		return String(m[0][1]), nil
-	case String:
-		return s, nil
-	default:
-		return "", errors.New("not as string")
-	}
-}
-
 // evalEval eval the scheme object and calculate its value
-func evalEval(args []Expression, env *Env) (Expression, error) {
+func evalEval(m *machine, args []Expression, env *Env) error {
 	if len(args) != 1 {
-		return UndefObj, errors.New("syntax error (requires 1 argument)")
-	}
-	expression := args[0]
-	arg, err := Eval(expression, env)
-	if err != nil {
-		return UndefObj, err
-	}
-	if !validEvalExp(arg) {
-		return UndefObj, errors.New("error: malformed list")
-	}
-	expStr := valueToString(arg)
-	t := NewTokenizerFromString(expStr)
-	tokens := t.Tokens()
-	var ret []Expression
-	ret, err = Parse(&tokens)
-	if err != nil {
-		return UndefObj, err
-	}
-	return EvalAll(ret, env)
-}
-
-func validEvalExp(exp Expression) bool {
-	switch p := exp.(type) {
-	case *Pair:
-		if !p.IsList() {
+		return errors.New("syntax error (requires 1 argument)")
+	}
+	m.evalThen(args[0], env, func(m *machine, arg Expression) error {
+		if isCircularList(arg, make(map[*Pair]int)) {
+			return fmt.Errorf("eval: %v is circular", valueToString(arg))
+		}
+		m.eval(datumToExpression(arg), env)
+		return nil
+	})
+	return nil
+}
+
+// isCircularList checks whether the pairs of the datum refer back to themselves, such data can't be converted to the
+// syntax tree. states marks the pairs being walked with 1 and the walked ones with 2.
+func isCircularList(datum Expression, states map[*Pair]int) bool {
+	var chain []*Pair
+	defer func() {
+		for _, p := range chain {
+			states[p] = 2
+		}
+	}()
+	for p, ok := datum.(*Pair); ok; p, ok = p.Cdr.(*Pair) {
+		switch states[p] {
+		case 1:
+			return true
+		case 2:
 			return false
 		}
-		return validEvalExp(p.Car) && validEvalExp(p.Cdr)
-	default:
-		return true
+		states[p] = 1
+		chain = append(chain, p)
+		if isCircularList(p.Car, states) {
+			return true
+		}
 	}
+	return false
 }
 
-func evalApply(args []Expression, env *Env) (Expression, error) {
+func evalApply(m *machine, args []Expression, env *Env) error {
 	if len(args) != 2 {
-		return UndefObj, errors.New("syntax error (requires 2 argument)")
-	}
-	procedure, err := Eval(args[0], env)
-	if err != nil {
-		return UndefObj, nil
-	}
-	arg, err := Eval(args[1], env)
-	if err != nil {
-		return UndefObj, nil
+		return errors.New("syntax error (requires 2 argument)")
 	}
-	if !isList(arg) {
-		return UndefObj, errors.New("argument must be a list")
-	}
-	argList := arg.(*Pair)
-	var argSlice = make([]Expression, 0, 1)
-	argSlice = append(argSlice, extractList(argList)...)
-	var expression []Expression
-	expression = append(expression, procedure)
-	expression = append(expression, argSlice...)
-	return Eval(expression, env)
+	return m.evalArgs(args, env, func(m *machine, values []Expression) error {
+		if !isList(values[1]) {
+			return errors.New("argument must be a list")
+		}
+		return m.apply(values[0], extractList(values[1]))
+	})
 }
 
-// load other scheme script files
-func evalLoad(expression []Expression, env *Env) (Expression, error) {
-	if len(expression) != 1 {
-		return UndefObj, errors.New("syntax error (requires 1 argument)")
-	}
-	argValue, err := Eval(expression[0], env)
-	if err != nil {
-		return UndefObj, err
-	}
-	switch v := argValue.(type) {
-	case String:
-		if err := loadFile(string(v), env); err != nil {
-			return UndefObj, err
-		}
-	case Quote:
-		if err := loadFile(string(v), env); err != nil {
-			return UndefObj, err
-		}
-	case *Pair:
-		if isList(v) {
-			expressions := extractList(v)
-			for _, p := range expressions {
-				ret, err := evalLoad([]Expression{p}, env)
-				if err != nil {
-					return ret, err
-				}
+// evalLoad evaluates the expressions of the scheme script files in env, the argument is the file name or the list of
+// the file names. (load "lib.scm")
+func evalLoad(m *machine, args []Expression, env *Env) error {
+	if len(args) != 1 {
+		return errors.New("syntax error (requires 1 argument)")
+	}
+	m.evalThen(args[0], env, func(m *machine, argValue Expression) error {
+		files := []Expression{argValue}
+		if p, ok := argValue.(*Pair); ok && isList(p) {
+			files = extractList(p)
+		}
+		var exps []Expression
+		for _, file := range files {
+			name, ok := stringValue(file)
+			if sym, isSymbol := file.(Symbol); isSymbol {
+				name, ok = sym.String(), true
+			}
+			if !ok {
+				return errors.New("argument can only contains string, quote or list")
+			}
+			fileExps, err := readFile(name)
+			if err != nil {
+				return err
 			}
+			exps = append(exps, fileExps...)
 		}
-	default:
-		return UndefObj, errors.New("argument can only contains string, quote or list")
-	}
-	return UndefObj, nil
+		m.push(func(m *machine, _ Expression) error {
+			m.ret(UndefObj)
+			return nil
+		})
+		m.evalSequence(exps, env)
+		return nil
+	})
+	return nil
 }
 
-func loadFile(filePath string, env *Env) error {
+// readFile reads the expressions of the scheme script file, the extension .scm is appended when missing.
+func readFile(filePath string) ([]Expression, error) {
 	ext := path.Ext(filePath)
 	if ext != ".scm" {
 		filePath += ".scm"
 	}
 	f, err := os.Open(filePath)
 	if err != nil {
-		return fmt.Errorf("load %s failed: %s", filePath, err)
+		return nil, fmt.Errorf("load %s failed: %s", filePath, err)
+	}
+	defer f.Close()
+	tokens := NewTokenizerFromReader(f).Tokens()
+	exps, err := Parse(&tokens)
+	if err != nil {
+		return nil, fmt.Errorf("load %s failed: %s", filePath, err)
 	}
-	i := NewFileInterpreterWithEnv(f, env)
-	return i.Run()
+	return exps, nil
 }
 
 func evalQuote(args []Expression, env *Env) (Expression, error) {
@@ -422,102 +331,239 @@ func evalQuote(args []Expression, env *Env) (Expression, error) {
 	}
 	exp := args[0]
 	switch v := exp.(type) {
-	case Number:
+	case Symbol:
 		return v, nil
-	case string:
-		if IsNumber(v) {
-			return expressionToNumber(exp)
-		}
-		if IsString(exp) {
-			return expToString(exp)
-		}
-		return Quote(v), nil
+	case *identifier:
+		return Symbol(symbolName(v)), nil
 	case []Expression:
-		var args []Expression
-		for _, exp := range v {
-			q, err := evalQuote([]Expression{exp}, env)
-			if err != nil {
+		var tail Expression = NilObj
+		if n := len(v); n >= 2 && isDot(v[n-2]) {
+			var err error
+			if tail, err = evalQuote(v[n-1:], env); err != nil {
 				return UndefObj, err
 			}
-			args = append(args, q)
+			v = v[:n-2]
 		}
-		return listImpl(args...)
+		for i := len(v) - 1; i >= 0; i-- {
+			q, err := evalQuote([]Expression{v[i]}, env)
+			if err != nil {
+				return UndefObj, err
+			}
+			tail = &Pair{q, tail}
+		}
+		return tail, nil
+	case vectorLiteral:
+		return quoteVector(v)
+	case bytevectorLiteral:
+		return v.bytevector(), nil
+	case Number, String, Char, bool, Keyword, *Vector, *Bytevector:
+		// the atoms read by the reader and the values inserted into the syntax tree, such as the expansion of
+		// define-macro, are data already
+		return v, nil
 	default:
 		return UndefObj, errors.New("invalid quote argument")
 	}
 }
 
-func evalLambda(args []Expression, env *Env) (Expression, error) {
-	if len(args) < 2 {
-		return nil, errors.New("not a valid lambda expression")
+// evalQuasiquote constructs the data of the template, the unquoted expressions are evaluated on the machine so the
+// continuations captured in them include the rest of the construction.
+func evalQuasiquote(m *machine, args []Expression, env *Env) error {
+	if len(args) != 1 {
+		return errors.New("syntax error (requires 1 argument)")
 	}
-	paramOperand := args[0]
-	body := args[1:]
-	var paramNames []Symbol
-	switch p := paramOperand.(type) {
+	return m.quasiquote(args[0], 1, env, func(m *machine, value Expression) error {
+		m.ret(value)
+		return nil
+	})
+}
+
+// quasiquote constructs the data of the template like quote and passes it to then, the unquote and unquote-splicing
+// forms at the depth 1 are evaluated, the depth increases by nested quasiquote and decreases by unquote.
+func (m *machine) quasiquote(template Expression, depth int, env *Env, then frame) error {
+	switch t := template.(type) {
 	case []Expression:
-		for _, e := range p {
-			sym, err := transExpressionToSymbol(e)
-			if err != nil {
-				return nil, err
+		if len(t) == 2 && isKeyword(t[0], "unquote") {
+			if depth == 1 {
+				m.evalThen(t[1], env, then)
+				return nil
 			}
-			paramNames = append(paramNames, sym)
+			return m.quasiquoteForm("unquote", t[1], depth-1, env, then)
 		}
-	case Expression:
-		sym, err := transExpressionToSymbol(p)
+		if len(t) == 2 && isKeyword(t[0], "quasiquote") {
+			return m.quasiquoteForm("quasiquote", t[1], depth+1, env, then)
+		}
+		var tail Expression
+		if n := len(t); n >= 2 && isDot(t[n-2]) {
+			tail, t = t[n-1], t[:n-2]
+		}
+		return m.quasiquoteElements(t, depth, env, func(m *machine, elements []Expression) error {
+			build := func(m *machine, list Expression) error {
+				for i := len(elements) - 1; i >= 0; i-- {
+					list = &Pair{elements[i], list}
+				}
+				return then(m, list)
+			}
+			if tail == nil {
+				return build(m, NilObj)
+			}
+			return m.quasiquote(tail, depth, env, build)
+		})
+	case vectorLiteral:
+		return m.quasiquoteElements(t, depth, env, func(m *machine, elements []Expression) error {
+			return then(m, &Vector{Elements: elements})
+		})
+	default:
+		v, err := evalQuote([]Expression{template}, env)
+		if err != nil {
+			return err
+		}
+		return then(m, v)
+	}
+}
+
+// quasiquoteForm constructs the data (name template) with the template quasiquoted at depth.
+func (m *machine) quasiquoteForm(name string, template Expression, depth int, env *Env, then frame) error {
+	return m.quasiquote(template, depth, env, func(m *machine, v Expression) error {
+		return then(m, &Pair{intern(name), &Pair{v, NilObj}})
+	})
+}
+
+// quasiquoteElements constructs the elements of list or vector template from left to right, splicing the values of
+// unquote-splicing, and passes them to then.
+func (m *machine) quasiquoteElements(templates []Expression, depth int, env *Env, then func(m *machine, elements []Expression) error) error {
+	var next func(m *machine, i int, elements []Expression) error
+	next = func(m *machine, i int, elements []Expression) error {
+		if i == len(templates) {
+			return then(m, elements)
+		}
+		// copy the elements, the frames may be resumed again by a continuation
+		add := func(m *machine, values ...Expression) error {
+			return next(m, i+1, append(elements[:len(elements):len(elements)], values...))
+		}
+		t, ok := templates[i].([]Expression)
+		if !ok || len(t) != 2 || !isKeyword(t[0], "unquote-splicing") {
+			return m.quasiquote(templates[i], depth, env, func(m *machine, v Expression) error {
+				return add(m, v)
+			})
+		}
+		if depth > 1 {
+			return m.quasiquoteForm("unquote-splicing", t[1], depth-1, env, func(m *machine, v Expression) error {
+				return add(m, v)
+			})
+		}
+		m.evalThen(t[1], env, func(m *machine, v Expression) error {
+			if !isList(v) {
+				return fmt.Errorf("unquote-splicing: %v is not a list", valueToString(v))
+			}
+			return add(m, extractList(v)...)
+		})
+		return nil
+	}
+	return next(m, 0, []Expression{})
+}
+
+// quoteVector constructs the *Vector of the literal, the elements are quoted as data.
+func quoteVector(v vectorLiteral) (*Vector, error) {
+	elements := make([]Expression, len(v))
+	for i, exp := range v {
+		q, err := evalQuote([]Expression{exp}, nil)
 		if err != nil {
 			return nil, err
 		}
-		paramNames = []Symbol{sym}
+		elements[i] = q
+	}
+	return &Vector{Elements: elements}, nil
+}
+
+// parseFormals reads the formal parameters such as (a b . rest) or a single symbol taking all arguments.
+func parseFormals(spec Expression) (params []Symbol, rest Symbol, err error) {
+	f, err := parseLambdaList(spec)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(f.optionals) > 0 || len(f.keys) > 0 {
+		return nil, "", fmt.Errorf("syntax error: #!optional and #!key are only allowed in lambda parameters %v", expToPrintString(spec))
+	}
+	return f.params, f.rest, nil
+}
+
+// bindFormals binds the values to the parameters in env, the rest parameter takes the list of the remaining values.
+func bindFormals(env *Env, params []Symbol, rest Symbol, values []Expression) error {
+	if len(values) < len(params) || (rest == "" && len(values) != len(params)) {
+		return fmt.Errorf("requires %d values but %d values provided", len(params), len(values))
+	}
+	for i, param := range params {
+		env.Set(param, values[i])
+	}
+	if rest != "" {
+		list, _ := listImpl(values[len(params):]...)
+		env.Set(rest, list)
+	}
+	return nil
+}
+
+func evalLambda(args []Expression, env *Env) (Expression, error) {
+	if len(args) < 2 {
+		return nil, errors.New("not a valid lambda expression")
 	}
-	return makeLambdaProcess(paramNames, body, env), nil
+	f, err := parseLambdaList(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return makeLambdaProcess(f, args[1:], env), nil
 }
 
-func evalDefine(args []Expression, env *Env) (Expression, error) {
+func evalDefine(m *machine, args []Expression, env *Env) error {
 	if len(args) < 2 {
-		return UndefObj, errors.New("syntax error, require more than two arguments")
+		return errors.New("syntax error, require more than two arguments")
 	}
 	// fetch the symbol/argument names and value/body
 	s, val := args[0], args[1:]
 	switch se := s.(type) {
 	case []Expression:
-		var symbols []Symbol
-		for _, e := range se {
-			sym, err := transExpressionToSymbol(e)
-			if err != nil {
-				return UndefObj, err
-			}
-			symbols = append(symbols, sym)
+		if len(se) == 0 {
+			return errors.New("define: bad syntax (missing procedure name)")
 		}
-		p := makeLambdaProcess(symbols[1:], val, env)
-		env.Set(Symbol(symbols[0]), p)
+		name, err := transExpressionToSymbol(se[0])
+		if err != nil {
+			return err
+		}
+		f, err := parseLambdaList(se[1:])
+		if err != nil {
+			return err
+		}
+		env.Set(name, makeLambdaProcess(f, val, env))
 	case Expression:
 		if len(val) != 1 {
-			return UndefObj, errors.New("define: bad syntax (multiple expressions after identifier)")
+			return errors.New("define: bad syntax (multiple expressions after identifier)")
 		}
 		sym, err := transExpressionToSymbol(se)
 		if err != nil {
-			return UndefObj, err
+			return err
 		}
-		val, err := Eval(val[0], env)
-		if err != nil {
-			return UndefObj, err
-		}
-		env.Set(sym, val)
+		m.evalThen(val[0], env, func(m *machine, value Expression) error {
+			env.Set(sym, value)
+			m.ret(UndefObj)
+			return nil
+		})
+		return nil
 	}
-	return UndefObj, nil
+	m.ret(UndefObj)
+	return nil
 }
 
 func transExpressionToSymbol(s Expression) (Symbol, error) {
-	if IsSymbol(s) {
-		s, _ := s.(string)
-		return Symbol(s), nil
+	switch v := s.(type) {
+	case *identifier:
+		return v.key, nil
+	case Symbol:
+		return v, nil
 	}
-	return "", fmt.Errorf("%v is not a symbol", s)
+	return "", fmt.Errorf("%v is not a symbol", valueToString(s))
 }
 
-func makeLambdaProcess(paramNames []Symbol, body []Expression, env *Env) *LambdaProcess {
-	return &LambdaProcess{paramNames, body, env}
+func makeLambdaProcess(f formals, body []Expression, env *Env) *LambdaProcess {
+	return &LambdaProcess{formals: f, body: body, env: env}
 }
 
 // EvalAll iterate the sequence of expressions and evaluate each one.
@@ -533,18 +579,15 @@ func EvalAll(exps []Expression, env *Env) (ret Expression, err error) {
 }
 
 func expressionToNumber(exp Expression) (Number, error) {
-	v := exp
-	if !IsNumber(v) {
-		return 0, fmt.Errorf("%v is not a number", v)
-	}
-	switch t := v.(type) {
+	switch t := exp.(type) {
 	case Number:
 		return t, nil
 	case string:
-		f, _ := strconv.ParseFloat(t, 64)
-		return Number(f), nil
+		if n, ok := parseNumber(t, 10); ok {
+			return n, nil
+		}
 	}
-	return 0, nil
+	return Integer(0), fmt.Errorf("%v is not a number", valueToString(exp))
 }
 
 func conditionOfIfExpression(exp []Expression) (Expression, error) {
@@ -571,123 +614,298 @@ func elseExpOfIfExpression(exp []Expression) (Expression, error) {
 	return exp[2], nil
 }
 
-func evalIf(args []Expression, env *Env) (Expression, error) {
+func evalIf(m *machine, args []Expression, env *Env) error {
 	if len(args) < 2 {
-		return UndefObj, errors.New("syntax error (requires 2 argument)")
+		return errors.New("syntax error (requires 2 argument)")
 	}
 	conditionExp, err := conditionOfIfExpression(args)
 	if err != nil {
-		return UndefObj, err
-	}
-	condition, err := Eval(conditionExp, env)
-	if err != nil {
-		return UndefObj, err
+		return err
 	}
-	if IsTrue(condition) {
-		return trueExpOfIfExpression(args)
-	}
-	return elseExpOfIfExpression(args)
+	m.evalThen(conditionExp, env, func(m *machine, condition Expression) error {
+		var next Expression
+		var err error
+		if IsTrue(condition) {
+			next, err = trueExpOfIfExpression(args)
+		} else {
+			next, err = elseExpOfIfExpression(args)
+		}
+		if err != nil {
+			return err
+		}
+		m.eval(next, env)
+		return nil
+	})
+	return nil
 }
 
-func evalBegin(args []Expression, env *Env) (Expression, error) {
+func evalBegin(m *machine, args []Expression, env *Env) error {
 	if len(args) < 1 {
-		return UndefObj, errors.New("syntax error (requires more than 1 arguments)")
-	}
-	for _, e := range args[:len(args)-1] {
-		Eval(e, env)
+		return errors.New("syntax error (requires more than 1 arguments)")
 	}
-	return args[len(args)-1], nil
+	m.evalSequence(args, env)
+	return nil
 }
 
-func evalCond(exp []Expression, env *Env) (Expression, error) {
-	equalIfExp, err := expandCond(exp)
-	if err != nil {
-		return UndefObj, err
+// evalBody evaluates the body of lambda and the let forms. The variables of the definitions in the body are bound in
+// a new scope before the body runs like letrec*, so the definitions can refer to each other and shadow the outer
+// variables in the whole body. Using the variables before their definitions run is an error.
+func (m *machine) evalBody(body []Expression, env *Env) {
+	body, names := scanDefinitions(body, env)
+	if len(names) > 0 {
+		env = &Env{outer: env, frame: make(map[Symbol]Expression)}
+		for _, name := range names {
+			env.Set(name, unassignedObj)
+		}
 	}
-	return Eval(equalIfExp, env)
+	m.evalSequence(body, env)
 }
 
-func makeIf(condition, trueExp, elseExp Expression) []Expression {
-	return []Expression{"if", condition, trueExp, elseExp}
+// scanDefinitions splices the begin forms of the body and returns the body with the variables defined in it, the
+// definitions after the expressions are scanned too so they never define the variables of the outer environment.
+func scanDefinitions(body []Expression, env *Env) ([]Expression, []Symbol) {
+	var scanned []Expression
+	var names []Symbol
+	for len(body) > 0 {
+		exp := body[0]
+		if isSyntaxForm(exp, env, "begin") {
+			body = append(append([]Expression{}, exp.([]Expression)[1:]...), body[1:]...)
+			continue
+		}
+		defined, _ := definedNames(exp, env)
+		names = append(names, defined...)
+		scanned = append(scanned, exp)
+		body = body[1:]
+	}
+	return scanned, names
 }
 
-func condClauses(exp []Expression) []Expression {
-	return exp[:]
+// definedNames returns the variables defined by the define or define-values form.
+func definedNames(exp Expression, env *Env) ([]Symbol, bool) {
+	switch {
+	case isSyntaxForm(exp, env, "define"):
+		form := exp.([]Expression)
+		if len(form) < 2 {
+			return nil, true
+		}
+		target := form[1]
+		if spec, ok := target.([]Expression); ok && len(spec) > 0 {
+			target = spec[0]
+		}
+		name, err := transExpressionToSymbol(target)
+		if err != nil {
+			return nil, true
+		}
+		return []Symbol{name}, true
+	case isSyntaxForm(exp, env, "define-values"):
+		form := exp.([]Expression)
+		if len(form) < 2 {
+			return nil, true
+		}
+		params, rest, err := parseFormals(form[1])
+		if err != nil {
+			return nil, true
+		}
+		if rest != "" {
+			params = append(params, rest)
+		}
+		return params, true
+	}
+	return nil, false
 }
 
-func expandCond(exp Expression) (Expression, error) {
-	e, ok := exp.([]Expression)
-	if !ok {
-		return UndefObj, fmt.Errorf("%v not a valid expression", exp)
+// isSyntaxForm checks whether the expression is the use of the syntax name, which is not shadowed in env.
+func isSyntaxForm(exp Expression, env *Env, name string) bool {
+	ops, ok := exp.([]Expression)
+	if !ok || len(ops) == 0 || !isKeyword(ops[0], name) {
+		return false
 	}
-	return condClausesToIf(condClauses(e))
+	head, err := env.findSymbol(ops[0])
+	s, ok := head.(*Syntax)
+	return err == nil && ok && s.name == name
+}
+
+func evalCond(m *machine, args []Expression, env *Env) error {
+	return m.evalCondClauses(args, env, func(m *machine) error {
+		m.ret(UndefObj)
+		return nil
+	})
 }
 
-func conditionOfClause(exp []Expression) (Expression, error) {
-	if len(exp) == 0 {
-		return UndefObj, fmt.Errorf("cannot find clause of %v", exp)
+// evalCondClauses evaluates the clauses (test expression ...), (test => receiver), (test) and (else expression ...)
+// in order, otherwise is called when no clause matches.
+func (m *machine) evalCondClauses(clauses []Expression, env *Env, otherwise func(m *machine) error) error {
+	if len(clauses) == 0 {
+		return otherwise(m)
 	}
-	return exp[0], nil
+	clause, ok := clauses[0].([]Expression)
+	if !ok || len(clause) == 0 {
+		return fmt.Errorf("%v not a valid clause", expToPrintString(clauses[0]))
+	}
+	if isKeyword(clause[0], "else") {
+		if len(clauses) != 1 {
+			return errors.New("else clause must in the last position")
+		}
+		m.evalSequence(clause[1:], env)
+		return nil
+	}
+	m.evalThen(clause[0], env, func(m *machine, test Expression) error {
+		switch {
+		case !IsTrue(test):
+			return m.evalCondClauses(clauses[1:], env, otherwise)
+		case len(clause) == 1:
+			m.ret(test)
+		case isKeyword(clause[1], "=>"):
+			if len(clause) != 3 {
+				return errors.New("syntax error: => requires a receiver")
+			}
+			m.evalThen(clause[2], env, func(m *machine, receiver Expression) error {
+				return m.apply(receiver, []Expression{test})
+			})
+		default:
+			m.evalSequence(clause[1:], env)
+		}
+		return nil
+	})
+	return nil
 }
 
-func processesOfClause(exp []Expression) (Expression, error) {
-	if len(exp) < 2 {
-		return UndefObj, errors.New("clause of expression not found")
+// evalCase evaluates the key and the first clause with a datum eqv? to the key, the clause (data => receiver) calls
+// the receiver with the key. (case x ((1 2) 'low) ((3) => f) (else 'high))
+func evalCase(m *machine, args []Expression, env *Env) error {
+	if len(args) < 1 {
+		return errors.New("case: syntax error (requires key and clauses)")
+	}
+	clauses := args[1:]
+	m.evalThen(args[0], env, func(m *machine, key Expression) error {
+		for i, exp := range clauses {
+			clause, ok := exp.([]Expression)
+			if !ok || len(clause) == 0 {
+				return fmt.Errorf("case: %v not a valid clause", expToPrintString(exp))
+			}
+			matched := isKeyword(clause[0], "else")
+			if matched && i != len(clauses)-1 {
+				return errors.New("case: else clause must in the last position")
+			}
+			if !matched {
+				data, ok := clause[0].([]Expression)
+				if !ok {
+					return fmt.Errorf("case: %v not a valid clause", expToPrintString(exp))
+				}
+				for _, datum := range data {
+					v, err := evalQuote([]Expression{datum}, env)
+					if err != nil {
+						return err
+					}
+					if isEqv(key, v) {
+						matched = true
+						break
+					}
+				}
+			}
+			if matched {
+				return m.evalCaseBody(clause[1:], env, key)
+			}
+		}
+		m.ret(UndefObj)
+		return nil
+	})
+	return nil
+}
+
+func (m *machine) evalCaseBody(body []Expression, env *Env, key Expression) error {
+	if len(body) == 0 || !isKeyword(body[0], "=>") {
+		m.evalSequence(body, env)
+		return nil
+	}
+	if len(body) != 2 {
+		return errors.New("case: syntax error (=> requires a receiver)")
 	}
-	return exp[1:], nil
+	m.evalThen(body[1], env, func(m *machine, receiver Expression) error {
+		return m.apply(receiver, []Expression{key})
+	})
+	return nil
 }
 
-func isElseClause(clause Expression) bool {
-	switch v := clause.(type) {
-	case []Expression:
-		return v[0] == "else"
-	default:
-		return false
+// conditionalBody creates the step of when and unless, which evaluate the body if the test is truthy or falsy.
+func conditionalBody(name string, when bool) syntaxStep {
+	return func(m *machine, args []Expression, env *Env) error {
+		if len(args) < 2 {
+			return fmt.Errorf("%s: syntax error (requires test and body)", name)
+		}
+		m.evalThen(args[0], env, func(m *machine, test Expression) error {
+			if IsTrue(test) == when {
+				m.evalSequence(args[1:], env)
+			} else {
+				m.ret(UndefObj)
+			}
+			return nil
+		})
+		return nil
 	}
 }
 
-func condClausesToIf(exp []Expression) (Expression, error) {
-	if IsNullExp(exp) {
-		// just a nil obj
-		return UndefObj, nil
+// evalDo runs the loop (do ((var init step) ...) (test result ...) body ...), the variables are bound to the values of
+// the steps in a fresh environment for each iteration, and the results are evaluated when the test is true.
+func evalDo(m *machine, args []Expression, env *Env) error {
+	if len(args) < 2 {
+		return errors.New("do: syntax error (requires bindings and test clause)")
 	}
-	first, ok := exp[0].([]Expression)
+	specs, ok := args[0].([]Expression)
 	if !ok {
-		return UndefObj, fmt.Errorf("%v not a valid expression", exp[0])
+		return errors.New("do: syntax error (not a valid binding)")
+	}
+	exit, ok := args[1].([]Expression)
+	if !ok || len(exit) == 0 {
+		return errors.New("do: syntax error (not a valid test clause)")
 	}
-	rest := exp[1:]
-	if isElseClause(first) {
-		if len(rest) != 0 {
-			return UndefObj, errors.New("else clause must in the last position: cond->if")
+	var symbols []Symbol
+	var inits, steps []Expression
+	for _, exp := range specs {
+		spec, ok := exp.([]Expression)
+		if !ok || len(spec) < 2 || len(spec) > 3 {
+			return errors.New("do: syntax error (not a valid binding)")
 		}
-		clause, err := processesOfClause(first)
+		sym, err := transExpressionToSymbol(spec[0])
 		if err != nil {
-			return UndefObj, err
-		}
-		return sequenceToExp(clause), nil
-	}
-	var condition, seq, clause Expression
-	condition, err := conditionOfClause(first)
-	if err != nil {
-		return UndefObj, err
-	}
-	clause, err = processesOfClause(first)
-	if err != nil {
-		return UndefObj, err
-	}
-	seq = sequenceToExp(clause)
-	elseIfClause, err := condClausesToIf(rest)
-	if err != nil {
-		return UndefObj, err
+			return err
+		}
+		symbols = append(symbols, sym)
+		inits = append(inits, spec[1])
+		// the variable without step keeps its value
+		step := spec[0]
+		if len(spec) == 3 {
+			step = spec[2]
+		}
+		steps = append(steps, step)
+	}
+	body := args[2:]
+	var loop func(m *machine, values []Expression) error
+	loop = func(m *machine, values []Expression) error {
+		loopEnv := &Env{outer: env, frame: make(map[Symbol]Expression)}
+		for i, sym := range symbols {
+			loopEnv.Set(sym, values[i])
+		}
+		m.evalThen(exit[0], loopEnv, func(m *machine, test Expression) error {
+			if IsTrue(test) {
+				m.evalSequence(exit[1:], loopEnv)
+				return nil
+			}
+			m.push(func(m *machine, _ Expression) error {
+				return m.evalArgs(steps, loopEnv, loop)
+			})
+			m.evalSequence(body, loopEnv)
+			return nil
+		})
+		return nil
 	}
-	return makeIf(condition, seq, elseIfClause), nil
-
+	return m.evalArgs(inits, env, loop)
 }
 
 func sequenceToExp(exp Expression) Expression {
 	switch exs := exp.(type) {
 	case []Expression:
-		ret := []Expression{"begin"}
+		ret := []Expression{Symbol("begin")}
 		ret = append(ret, exs...)
 		return ret
 	case Expression:
@@ -2,6 +2,7 @@ package goscheme
 
 import (
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -9,29 +10,29 @@ func TestEval(t *testing.T) {
 	builtinEnv := setupBuiltinEnv()
 	var ret Expression
 	ret, _ = Eval("3", builtinEnv)
-	assert.Equal(t, ret, Number(3))
+	assert.Equal(t, ret, Integer(3))
 	Eval([]Expression{"define", "x", "3"}, builtinEnv)
 	ret, _ = Eval("x", builtinEnv)
-	assert.Equal(t, Number(3), ret)
+	assert.Equal(t, Integer(3), ret)
 	Eval([]Expression{"define", []Expression{"fn", "y"}, []Expression{"+", "x", "y"}}, builtinEnv)
 	ret, _ = Eval([]Expression{"fn", "x"}, builtinEnv)
-	assert.Equal(t, Number(6), ret)
+	assert.Equal(t, Integer(6), ret)
 
 	// test begin
 	ret, _ = Eval([]Expression{"begin", "1"}, builtinEnv)
-	assert.Equal(t, Number(1), ret)
+	assert.Equal(t, Integer(1), ret)
 	ret, _ = Eval([]Expression{"begin", "#t"}, builtinEnv)
 	assert.Equal(t, true, ret)
 	ret, _ = Eval([]Expression{"begin", "1", []Expression{"+", "1", "2", "3"}}, builtinEnv)
-	assert.Equal(t, Number(6), ret)
+	assert.Equal(t, Integer(6), ret)
 
 	// test if
 	testCases := []struct {
 		input    Expression
 		expected Expression
 	}{
-		{[]Expression{"if", "#t", "1", "0"}, Number(1)},
-		{[]Expression{"if", "#f", "1", "0"}, Number(0)},
+		{[]Expression{"if", "#t", "1", "0"}, Integer(1)},
+		{[]Expression{"if", "#f", "1", "0"}, Integer(0)},
 		{[]Expression{"if", "#f", "1"}, UndefObj},
 	}
 	for _, c := range testCases {
@@ -44,9 +45,9 @@ func TestEval(t *testing.T) {
 		input    Expression
 		expected Expression
 	}{
-		{[]Expression{"cond", []Expression{"#t", "1", "2"}}, Number(2)},
+		{[]Expression{"cond", []Expression{"#t", "1", "2"}}, Integer(2)},
 		{[]Expression{"cond", []Expression{"#f", "1", "2"}}, UndefObj},
-		{[]Expression{"cond", []Expression{"#f", "1", "2"}, []Expression{"#t", "2"}}, Number(2)},
+		{[]Expression{"cond", []Expression{"#f", "1", "2"}, []Expression{"#t", "2"}}, Integer(2)},
 		{[]Expression{"cond", []Expression{"#f", "1", "2"}, []Expression{"else", `"else clause"`}}, String(`else clause`)},
 	}
 	for _, c := range testCases {
@@ -70,7 +71,7 @@ func TestEval(t *testing.T) {
 
 	// test lambda
 	ret, _ = EvalAll(strToToken("((lambda (x y) (+ x y)) 1 2)"), builtinEnv)
-	assert.Equal(t, Number(3), ret)
+	assert.Equal(t, Integer(3), ret)
 
 	// test recursion
 	tz := NewTokenizerFromString(
@@ -90,13 +91,13 @@ func TestEval(t *testing.T) {
 		input    Expression
 		expected Expression
 	}{
-		{[]Expression{"fact1", "2"}, Number(2)},
-		{[]Expression{"fact1", "6"}, Number(720)},
-		{[]Expression{"fact1", "0"}, Number(1)},
+		{[]Expression{"fact1", "2"}, Integer(2)},
+		{[]Expression{"fact1", "6"}, Integer(720)},
+		{[]Expression{"fact1", "0"}, Integer(1)},
 		// tail recursion
-		{[]Expression{"fact2", "2"}, Number(2)},
-		{[]Expression{"fact2", "6"}, Number(720)},
-		{[]Expression{"fact2", "0"}, Number(1)},
+		{[]Expression{"fact2", "2"}, Integer(2)},
+		{[]Expression{"fact2", "6"}, Integer(720)},
+		{[]Expression{"fact2", "0"}, Integer(1)},
 	}
 	for _, c := range testCases {
 		ret, _ = Eval(c.input, builtinEnv)
@@ -108,64 +109,64 @@ func TestEval(t *testing.T) {
 		input    Expression
 		expected Expression
 	}{
-		{[]Expression{"cons", "1", "2"}, &Pair{Number(1), Number(2)}},
+		{[]Expression{"cons", "1", "2"}, &Pair{Integer(1), Integer(2)}},
 	}
 	for _, c := range testCases {
 		ret, _ = Eval(c.input, builtinEnv)
 		assert.Equal(t, c.expected, ret)
 	}
 	ret, _ = Eval([]Expression{"cons", "1", "2"}, builtinEnv)
-	assert.Equal(t, &Pair{Number(1), Number(2)}, ret)
+	assert.Equal(t, &Pair{Integer(1), Integer(2)}, ret)
 
 	//// test list
 	ret, _ = Eval([]Expression{"list", "1", "2"}, builtinEnv)
-	assert.Equal(t, &Pair{Number(1), &Pair{Number(2), NilObj}}, ret)
+	assert.Equal(t, &Pair{Integer(1), &Pair{Integer(2), NilObj}}, ret)
 	ret, _ = Eval([]Expression{"list", "1"}, builtinEnv)
-	assert.Equal(t, &Pair{Number(1), NilObj}, ret)
+	assert.Equal(t, &Pair{Integer(1), NilObj}, ret)
 	ret, _ = Eval([]Expression{"list"}, builtinEnv)
 	assert.Equal(t, NilObj, ret)
 	ret, _ = Eval([]Expression{"list", "1", []Expression{"cons", "1", []Expression{}}}, builtinEnv)
-	assert.Equal(t, &Pair{Number(1), &Pair{&Pair{Number(1), NilObj}, NilObj}}, ret)
+	assert.Equal(t, &Pair{Integer(1), &Pair{&Pair{Integer(1), NilObj}, NilObj}}, ret)
 
 	//// test append
 	ret, _ = EvalAll(strToToken("(append (cons 1 ()) 2)"), builtinEnv)
-	assert.Equal(t, &Pair{Number(1), &Pair{Number(2), NilObj}}, ret)
+	assert.Equal(t, &Pair{Integer(1), &Pair{Integer(2), NilObj}}, ret)
 	ret, _ = EvalAll(strToToken("(append () 2)"), builtinEnv)
-	assert.Equal(t, &Pair{Number(2), NilObj}, ret)
+	assert.Equal(t, &Pair{Integer(2), NilObj}, ret)
 	ret, _ = EvalAll(strToToken("(append (cons 1 ()) (cons 2 ()))"), builtinEnv)
-	assert.Equal(t, &Pair{Number(1), &Pair{Number(2), NilObj}}, ret)
+	assert.Equal(t, &Pair{Integer(1), &Pair{Integer(2), NilObj}}, ret)
 	ret, _ = EvalAll(strToToken("(append (cons 1 ()) ())"), builtinEnv)
-	assert.Equal(t, &Pair{Number(1), NilObj}, ret)
+	assert.Equal(t, &Pair{Integer(1), NilObj}, ret)
 	ret, _ = EvalAll(strToToken("(append (cons 1 ()) 2 3)"), builtinEnv)
-	assert.Equal(t, &Pair{Number(1), &Pair{Number(2), &Pair{Number(3), NilObj}}}, ret)
+	assert.Equal(t, &Pair{Integer(1), &Pair{Integer(2), &Pair{Integer(3), NilObj}}}, ret)
 	ret, _ = EvalAll(strToToken("(append (cons 1 ()) (cons 2 ()) (cons 3 ()))"), builtinEnv)
-	assert.Equal(t, &Pair{Number(1), &Pair{Number(2), &Pair{Number(3), NilObj}}}, ret)
+	assert.Equal(t, &Pair{Integer(1), &Pair{Integer(2), &Pair{Integer(3), NilObj}}}, ret)
 
 	// test quote
 	ret, _ = EvalAll(strToToken("(quote (1 2))"), builtinEnv)
-	assert.Equal(t, &Pair{Number(1), &Pair{Number(2), NilObj}}, ret)
+	assert.Equal(t, &Pair{Integer(1), &Pair{Integer(2), NilObj}}, ret)
 	ret, _ = EvalAll(strToToken("(quote 1)"), builtinEnv)
-	assert.Equal(t, Number(1), ret)
+	assert.Equal(t, Integer(1), ret)
 	ret, _ = EvalAll(strToToken(`(quote "x")`), builtinEnv)
 	assert.Equal(t, String("x"), ret)
 	ret, _ = EvalAll(strToToken(`(quote (1 "x"))`), builtinEnv)
-	assert.Equal(t, &Pair{Number(1), &Pair{String("x"), NilObj}}, ret)
+	assert.Equal(t, &Pair{Integer(1), &Pair{String("x"), NilObj}}, ret)
 	ret, _ = EvalAll(strToToken(`(quote (cons 1 "x"))`), builtinEnv)
-	assert.Equal(t, &Pair{Quote("cons"), &Pair{Number(1), &Pair{String("x"), NilObj}}}, ret)
+	assert.Equal(t, &Pair{Symbol("cons"), &Pair{Integer(1), &Pair{String("x"), NilObj}}}, ret)
 	ret, _ = EvalAll(strToToken(`(quote (1 (2 3) 4))`), builtinEnv)
 	assert.Equal(t, &Pair{
-		Number(1),
+		Integer(1),
 		&Pair{
-			&Pair{Number(2), &Pair{Number(3), NilObj}}, &Pair{Number(4), NilObj}}},
+			&Pair{Integer(2), &Pair{Integer(3), NilObj}}, &Pair{Integer(4), NilObj}}},
 		ret)
 	ret, _ = EvalAll(strToToken("'(1 2)"), builtinEnv)
-	assert.Equal(t, &Pair{Number(1), &Pair{Number(2), NilObj}}, ret)
+	assert.Equal(t, &Pair{Integer(1), &Pair{Integer(2), NilObj}}, ret)
 	ret, _ = EvalAll(strToToken("'x"), builtinEnv)
-	assert.Equal(t, Quote("x"), ret)
+	assert.Equal(t, Symbol("x"), ret)
 	ret, _ = EvalAll(strToToken("'(cons define 3)"), builtinEnv)
-	assert.Equal(t, &Pair{Quote("cons"), &Pair{Quote("define"), &Pair{Number(3), NilObj}}}, ret)
+	assert.Equal(t, &Pair{Symbol("cons"), &Pair{Symbol("define"), &Pair{Integer(3), NilObj}}}, ret)
 	ret, _ = EvalAll(strToToken("''(cons define 3)"), builtinEnv)
-	assert.Equal(t, &Pair{Quote("quote"), &Pair{&Pair{Quote("cons"), &Pair{Quote("define"), &Pair{Number(3), NilObj}}}, NilObj}}, ret)
+	assert.Equal(t, &Pair{Symbol("quote"), &Pair{&Pair{Symbol("cons"), &Pair{Symbol("define"), &Pair{Integer(3), NilObj}}}, NilObj}}, ret)
 }
 
 // test built in procedures
@@ -182,22 +183,22 @@ func TestEval3(t *testing.T) {
 	}{
 		{`
 				;comment
-				3`, Number(3)},
+				3`, Integer(3)},
 		{`
 				;comment
 				3
-				;comment`, Number(3)},
+				;comment`, Integer(3)},
 		{`
 				; comment
 				(define x 3)
 				; comment 2
-				x`, Number(3)},
+				x`, Integer(3)},
 		{`
 				;comment
 				(define (func ; comment
 						 x)
 					x)
-				(func 3)`, Number(3)},
+				(func 3)`, Integer(3)},
 		{`
 				;comment
 				(define (func ; comment
@@ -218,26 +219,26 @@ func TestEval4(t *testing.T) {
 		input    string
 		expected Expression
 	}{
-		{`(eval 3)`, Number(3)},
-		{`(eval '3)`, Number(3)},
-		{`(eval '(begin (display "") 3))`, Number(3)},
+		{`(eval 3)`, Integer(3)},
+		{`(eval '3)`, Integer(3)},
+		{`(eval '(begin (display "") 3))`, Integer(3)},
 		{`
 			(define fn '*)
 (define x 3)
 (define y (list '+ x 5))
 (define z (list fn 10 y))
-(eval y)`, Number(8)},
+(eval y)`, Integer(8)},
 		{`
 			(define fn '*)
 (define x 3)
 (define y (list '+ x 5))
 (define z (list fn 10 y))
-(eval z)`, Number(80)},
-		{`(define x 3) (eval 'x)`, Number(3)},
-		{`(define x 3) (eval ''x)`, Quote("x")},
+(eval z)`, Integer(80)},
+		{`(define x 3) (eval 'x)`, Integer(3)},
+		{`(define x 3) (eval ''x)`, Symbol("x")},
 		{`(apply display '(3))`, UndefObj},
-		{`(apply (lambda x x) '(3))`, Number(3)},
-		{`(apply (lambda (x y) (+ x y)) '(3 4))`, Number(7)},
+		{`(apply (lambda x (car x)) '(3))`, Integer(3)},
+		{`(apply (lambda (x y) (+ x y)) '(3 4))`, Integer(7)},
 	}
 	for _, c := range testCases {
 		env := setupBuiltinEnv()
@@ -263,8 +264,8 @@ func TestEval5(t *testing.T) {
 		expected Expression
 	}{
 		{`(thunk? (delay (+ 1 2)))`, true},
-		{`(force (delay (+ 1 2)))`, Number(3)},
-		{`(define (try a b) (if (= a 0) b a)) (try 1 (delay (+ 1 "x")))`, Number(1)},
+		{`(force (delay (+ 1 2)))`, Integer(3)},
+		{`(define (try a b) (if (= a 0) b a)) (try 1 (delay (+ 1 "x")))`, Integer(1)},
 		// eval error
 		{`(define (try a b) (if (= a 0) (force b) (force a))) (try 0 (delay (+ 1 "x")))`, UndefObj},
 	}
@@ -299,15 +300,15 @@ func TestEval7(t *testing.T) {
 		expected Expression
 	}{
 		{`(let ((x 2) (y 3))
-  					(* x y))`, Number(6)},
+  					(* x y))`, Integer(6)},
 		{`(let ((x 2) (y 3))
   					(let ((foo (lambda (z) (+ x y z)))
         				(x 7))
-    					(foo 4))) `, Number(9)},
+    					(foo 4))) `, Integer(9)},
 		{`(let ((x 2) (y 3))
   					(let* ((x 7)
          				(z (+ x y)))
-						(* z x)))`, Number(70)},
+						(* z x)))`, Integer(70)},
 		{`(letrec (
 					(zero? (lambda (x) (= x 0)))
 					(even?
@@ -325,7 +326,7 @@ func TestEval7(t *testing.T) {
 		{`(define (f a)
 					(let ((b 3)) (set! a 3))
 					a)
-				(f 4)`, Number(3)},
+				(f 4)`, Integer(3)},
 	}
 	for _, c := range testCases {
 		env := setupBuiltinEnv()
@@ -334,6 +335,192 @@ func TestEval7(t *testing.T) {
 	}
 }
 
+func TestQuasiquote(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"`(1 ,(+ 1 1) 3)", "(1 2 3)"},
+		{"`(a ,@(list 1 2) b)", "(a 1 2 b)"},
+		{"`(a ,@'() b)", "(a b)"},
+		{"`(1 . ,(+ 1 1))", "(1 . 2)"},
+		{"`#(1 ,(+ 1 1) ,@(list 3 4))", "#(1 2 3 4)"},
+		{"`(x `(y ,(z ,(+ 1 2))))", "(x (quasiquote (y (unquote (z 3)))))"},
+		{"`(1 `(,@(a ,@(list 2 3))))", "(1 (quasiquote ((unquote-splicing (a 2 3)))))"},
+		{"(quasiquote (1 (unquote (* 2 3))))", "(1 6)"},
+		{"`x", "x"},
+		{"`,(+ 1 2)", "3"},
+		{"(let ((name 'a)) `(list ,name ',name))", "(list a (quote a))"},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+	for _, input := range []string{"`(1 ,@2)", "(quasiquote)", "`(,undefined-variable)"} {
+		_, err := EvalAll(strToToken(input), env)
+		assert.NotNil(t, err, input)
+	}
+}
+
+func TestLambdaParameters(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"((lambda args args) 1 2 3)", "(1 2 3)"},
+		{"((lambda args args))", "()"},
+		{"((lambda (a b . rest) (list a b rest)) 1 2 3 4)", "(1 2 (3 4))"},
+		{"((lambda (a b . rest) (list a b rest)) 1 2)", "(1 2 ())"},
+		{"(define (f . args) args) (f 1 2)", "(1 2)"},
+		{"(define (f a . rest) (cons a rest)) (f 1 2 3)", "(1 2 3)"},
+		{"(define (f a #!optional b (c (+ a 1))) (list a b c)) (f 1)", "(1 #f 2)"},
+		{"(define (f a #!optional b (c (+ a 1))) (list a b c)) (f 1 2 3)", "(1 2 3)"},
+		{"(define (f #!key (x 1) y) (list x y)) (f)", "(1 #f)"},
+		{"(define (f #!key (x 1) y) (list x y)) (f y: 2 x: 3)", "(3 2)"},
+		{"(define (f a #!optional b #!key (c b)) (list a b c)) (f 1 2 c: 3)", "(1 2 3)"},
+		{"(define (f #!key x #!rest r) (list x r)) (f x: 1 y: 2)", "(1 (x: 1 y: 2))"},
+		{"'x:", "x:"},
+		// name: is only a keyword in the calls to the procedures with #!key parameters
+		{"(define x: 5) (define (g a) a) (list (symbol? 'x:) x: (g x:))", "(#t 5 5)"},
+		{"(define (f #!key (x 1) y) (list x y)) (apply f (list 'y: 2))", "(1 2)"},
+		{"(lambda (a #!optional (b 1) . r) a)", "(lambda (a #!optional (b 1) #!rest r) a)"},
+		{"(lambda (a . r) a)", "(lambda (a . r) a)"},
+		{"(define plus (case-lambda ((x) x) ((x y) (+ x y)) ((x . rest) (+ x (apply plus rest))))) (list (plus 1) (plus 1 2) (plus 1 2 3))", "(1 3 6)"},
+		{"((case-lambda ((x #!optional (y 10)) (+ x y)) (args args)) 1)", "11"},
+		{"((case-lambda ((x) x) (args args)))", "()"},
+	}
+	for _, c := range testCases {
+		env := setupBuiltinEnv()
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+	for _, input := range []string{
+		"((lambda (a b . rest) a) 1)",
+		"((lambda (a #!optional b) a) 1 2 3)",
+		"((lambda (#!key x) x) y: 1)",
+		"((lambda (#!key x) x) x:)",
+		"((lambda (#!key x) x) 1 2)",
+		"((case-lambda ((x) x)) 1 2)",
+	} {
+		_, err := EvalAll(strToToken(input), setupBuiltinEnv())
+		assert.NotNil(t, err, input)
+	}
+}
+
+func TestIterationAndConditionals(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"(let loop ((i 0) (acc '())) (if (= i 3) acc (loop (+ i 1) (cons i acc))))", "(2 1 0)"},
+		{"(let loop ((i 0)) (if (< i 10000) (loop (+ i 1)) i))", "10000"},
+		{"(let f () 1)", "1"},
+		{"(define (loop) 1) (let loop ((x (loop))) x)", "1"},
+		{"(do ((i 0 (+ i 1)) (acc '() (cons i acc))) ((= i 3) acc))", "(2 1 0)"},
+		{"(do ((vec (make-vector 5)) (i 0 (+ i 1))) ((= i 5) vec) (vector-set! vec i i))", "#(0 1 2 3 4)"},
+		{"(do ((i 0 (+ i 1))) ((= i 10000) i))", "10000"},
+		{"(do ((i 0 (+ i 1))) ((= i 1)))", "<UNDEF>"},
+		{"(case (* 2 3) ((2 3 5 7) 'prime) ((1 4 6 8 9) 'composite))", "composite"},
+		{"(case (car '(c d)) ((a e i o u) 'vowel) ((w y) 'semivowel) (else => (lambda (x) x)))", "c"},
+		{"(case 5 ((5) => (lambda (x) (* x 2))) (else 0))", "10"},
+		{"(case #\\a ((#\\a) 'a) (else 'other))", "a"},
+		{"(case 1 ((2) 'two))", "<UNDEF>"},
+		{"(case 2.0 ((2) 'exact) (else 'inexact))", "inexact"},
+		{"(when (> 1 0) 'a 'b)", "b"},
+		{"(when (< 1 0) 'a)", "<UNDEF>"},
+		{"(unless (< 1 0) 'a 'b)", "b"},
+		{"(unless (> 1 0) 'a)", "<UNDEF>"},
+		{"(cond (#f 1) ((+ 1 1) => (lambda (x) (* x 3))) (else #f))", "6"},
+		{"(cond ((> 3 2) 'greater) ((< 3 2) 'less))", "greater"},
+		{"(cond (#f 1) (2))", "2"},
+		{"(define (count n) (cond ((= n 0) 'done) (else (count (- n 1))))) (count 10000)", "done"},
+		{"(define (count n) (when (> n 0) (count (- n 1)))) (count 10000)", "<UNDEF>"},
+		{"(define (count n) (case n ((0) 'done) (else (count (- n 1))))) (count 10000)", "done"},
+	}
+	for _, c := range testCases {
+		env := setupBuiltinEnv()
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+	for _, input := range []string{
+		"(do ((i 0)))",
+		"(do ((i)) (#t))",
+		"(case 1 (else 1) ((1) 2))",
+		"(case 1 (1 2))",
+		"(case 1 ((1) =>))",
+		"(when #t)",
+		"(let loop ((i)) i)",
+	} {
+		_, err := EvalAll(strToToken(input), setupBuiltinEnv())
+		assert.NotNil(t, err, input)
+	}
+}
+
+func TestInternalDefinitions(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"(define (f) (define (ev? n) (if (= n 0) #t (od? (- n 1)))) (define (od? n) (if (= n 0) #f (ev? (- n 1)))) (ev? 10)) (f)", "#t"},
+		{"(define x 'outer) (define (f) (display \"\") (define x 'inner) x) (list (f) x)", "(inner outer)"},
+		{"(define x 'outer) (define (f) (define x 'inner) x) (list (f) x)", "(inner outer)"},
+		{"(define (f a) (define a 2) a) (f 1)", "2"},
+		{"(let () (begin (define a 1) (define b (+ a 1))) (list a b))", "(1 2)"},
+		{"(let* ((a 1)) (define-values (b c) (values a 2)) (list a b c))", "(1 1 2)"},
+		{"(letrec ((a 1)) (define (g) b) (define b a) (g))", "1"},
+		{"(let ((define list)) (define 1 2))", "(1 2)"},
+		{"(define (counter) (define n 0) (lambda () (set! n (+ n 1)) n)) (define c (counter)) (c) (c)", "2"},
+		{"(define n 0) (define (f) (let ((a 1)) (let ((b 2)) (set! n (+ n a b))))) (f) (f) n", "6"},
+		{"(define (f) (let loop ((i 0)) (if (< i 3) (begin (set! total (+ total i)) (loop (+ i 1)))))) (define total 0) (f) total", "3"},
+	}
+	for _, c := range testCases {
+		env := setupBuiltinEnv()
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+	_, err := EvalAll(strToToken("(define (f) (set! undefined-variable 1)) (f)"), setupBuiltinEnv())
+	assert.NotNil(t, err)
+
+	// the variables are used before their definitions run
+	for _, input := range []string{
+		"(define x 'outer) (define (f) (define y x) (define x 'inner) y) (f)",
+		"(define (bad) (define a b) (define b 1) a) (bad)",
+		"(letrec ((a b) (b 1)) a)",
+		"(define x 1) (define (f) (set! x (+ x 1)) (define x 10) x) (f)",
+	} {
+		_, err := EvalAll(strToToken(input), setupBuiltinEnv())
+		if assert.NotNil(t, err, input) {
+			assert.Contains(t, err.Error(), "used before its definition", input)
+		}
+	}
+	env := setupBuiltinEnv()
+	_, err = EvalAll(strToToken("(define x 1) (define (f) (set! x 2) (define x 10) x) (f)"), env)
+	assert.NotNil(t, err)
+	ret, err := EvalAll(strToToken("x"), env)
+	assert.Nil(t, err)
+	assert.Equal(t, "1", valueToString(ret))
+}
+
+func TestReadDatums(t *testing.T) {
+	env := setupBuiltinEnv()
+	// a symbol whose name looks like a string is still a symbol after the expansion
+	ret, err := EvalAll(strToToken(`(define-macro (m) (list 'quote (string->symbol "\"x\""))) (m)`), env)
+	assert.Nil(t, err)
+	assert.Equal(t, Symbol(`"x"`), ret)
+	_, err = EvalAll(strToToken(`(define-macro (v) (string->symbol "\"x\"")) (v)`), env)
+	assert.NotNil(t, err)
+	// the expressions built by hand are read like Parse
+	ret, err = Eval([]Expression{"string-append", `"a"`, []Expression{"symbol->string", []Expression{"quote", "b"}}}, env)
+	assert.Nil(t, err)
+	assert.Equal(t, NewMutableString("ab"), ret)
+	assert.Equal(t, intern("x"), intern("x"))
+}
+
 func TestIsSyntaxExpression(t *testing.T) {
 	assert.Equal(t, true, IsSyntaxExpression([]Expression{"begin"}))
 }
@@ -344,3 +531,61 @@ func strToToken(input string) []Expression {
 	expressions, _ := Parse(&tokens)
 	return expressions
 }
+
+func TestDottedPairs(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"'(a . b)", "(a . b)"},
+		{"(cdr '(a . b))", "b"},
+		{"'(1 2 . 3)", "(1 2 . 3)"},
+		{"'(1 . (2 3))", "(1 2 3)"},
+		{"'((a . 1) (b . 2))", "((a . 1) (b . 2))"},
+		{"(assq 'b '((a . 1) (b . 2)))", "(b . 2)"},
+		{"(cdr (cdr '(1 . (2 . 3))))", "3"},
+		{"(equal? '(1 (2 . #(3)) . 4) (cons 1 (cons (cons 2 (vector 3)) 4)))", "#t"},
+		{"`(1 . ,(+ 1 1))", "(1 . 2)"},
+		{"(eval '(apply + '(1 . (2 3))))", "6"},
+		{"(eval '(quote (1 . 2)))", "(1 . 2)"},
+		{"(eval '((lambda (a . rest) rest) 1 2 3))", "(2 3)"},
+		{"(eval '(define (f . args) args)) (f 1 2)", "(1 2)"},
+		{"(eval '(define-syntax tail-of (syntax-rules () ((_ a . b) 'b)))) (tail-of 1 2 3)", "(2 3)"},
+		{"(eval '(define (g #!key (x 1)) x)) (eval '(g x: 2))", "2"},
+		{`(eval '(quote (a "b\"c" . #(1 (2 . 3)))))`, `(a "b\"c" . #(1 (2 . 3)))`},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+
+	// circular data can't be evaluated
+	_, err := EvalAll(strToToken("(define l (list 'quote 1)) (set-cdr! (cdr l) l) (eval l)"), env)
+	assert.NotNil(t, err)
+
+	// the external representation of the data read back by read is the same
+	input := "(a . b) (1 (2 . 3) . #(4 (5 . 6))) ((x . 1) (y . \"s\")) (quote (a . b))"
+	standardInput = NewTokenizerFromString(input)
+	var written []string
+	for {
+		ret, err := EvalAll(strToToken("(read)"), env)
+		assert.Nil(t, err)
+		if ret == EOFObj {
+			break
+		}
+		written = append(written, valueToString(ret))
+	}
+	assert.Equal(t, input, strings.Join(written, " "))
+	ret, err := EvalAll(strToToken("(eof-object? (read))"), env)
+	assert.Nil(t, err)
+	assert.Equal(t, true, ret)
+
+	standardInput = NewTokenizerFromString("(a . b c)")
+	_, err = EvalAll(strToToken("(read)"), env)
+	assert.NotNil(t, err)
+	standardInput = NewTokenizerFromString("(a")
+	_, err = EvalAll(strToToken("(read)"), env)
+	assert.NotNil(t, err)
+}
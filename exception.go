@@ -0,0 +1,187 @@
+package goscheme
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrorObject is the condition raised by error and the builtin failures. Should only use with pointer.
+type ErrorObject struct {
+	message   string
+	irritants []Expression
+}
+
+// String returns the string representing the *ErrorObject such as #<error "message" 1 2>.
+func (e *ErrorObject) String() string {
+	var buf strings.Builder
+	buf.WriteString("#<error ")
+	buf.WriteString(String(e.message).String())
+	for _, irritant := range e.irritants {
+		buf.WriteString(" ")
+		buf.WriteString(valueToString(irritant))
+	}
+	buf.WriteString(">")
+	return buf.String()
+}
+
+// describe returns the message followed by the irritants.
+func (e *ErrorObject) describe() string {
+	parts := []string{e.message}
+	for _, irritant := range e.irritants {
+		parts = append(parts, valueToString(irritant))
+	}
+	return strings.Join(parts, " ")
+}
+
+// IsErrorObject checks whether the expression value is an *ErrorObject.
+func IsErrorObject(exp Expression) bool {
+	_, ok := exp.(*ErrorObject)
+	return ok
+}
+
+// raisedError carries the object raised without any handler out of the machine.
+type raisedError struct {
+	payload Expression
+}
+
+func (r *raisedError) Error() string {
+	if e, ok := r.payload.(*ErrorObject); ok {
+		return e.describe()
+	}
+	return "uncaught exception: " + valueToString(r.payload)
+}
+
+// condition returns the object passed to the exception handlers for the error, the builtin failures are converted to
+// *ErrorObject.
+func condition(err error) Expression {
+	if r, ok := err.(*raisedError); ok {
+		return r.payload
+	}
+	return &ErrorObject{message: err.Error()}
+}
+
+// exceptionHandler is the handler stack installed by with-exception-handler and guard, the innermost first.
+type exceptionHandler struct {
+	handler Expression
+	parent  *exceptionHandler
+}
+
+// raise calls the current handler with the object in the dynamic environment of raise except the handler stack,
+// which is the outer handlers while the handler runs. The value of the handler is returned for raise-continuable,
+// returning from the handler of a non-continuable raise raises a secondary exception.
+func (m *machine) raise(obj Expression, continuable bool) error {
+	h := m.handlers
+	if h == nil {
+		return &raisedError{payload: obj}
+	}
+	m.handlers = h.parent
+	m.push(func(m *machine, value Expression) error {
+		if !continuable {
+			return m.raise(&ErrorObject{message: "exception handler returned from non-continuable raise", irritants: []Expression{obj}}, false)
+		}
+		m.handlers = h
+		m.ret(value)
+		return nil
+	})
+	return m.apply(h.handler, []Expression{obj})
+}
+
+func raiseFunc(args ...Expression) (Expression, error) {
+	return UndefObj, &raisedError{payload: args[0]}
+}
+
+func raiseContinuable(m *machine, args ...Expression) error {
+	return m.raise(args[0], true)
+}
+
+// withExceptionHandler calls the thunk with the handler installed.
+func withExceptionHandler(m *machine, args ...Expression) error {
+	handler, thunk := args[0], args[1]
+	outer := m.handlers
+	m.handlers = &exceptionHandler{handler: handler, parent: outer}
+	m.push(func(m *machine, value Expression) error {
+		m.handlers = outer
+		m.ret(value)
+		return nil
+	})
+	return m.apply(thunk, nil)
+}
+
+func errorFunc(args ...Expression) (Expression, error) {
+	message, err := expressionToGoString(args[0])
+	if err != nil {
+		return UndefObj, fmt.Errorf("error: %s", err)
+	}
+	return UndefObj, &raisedError{payload: &ErrorObject{message: message, irritants: args[1:]}}
+}
+
+func isErrorObjectFunc(args ...Expression) (Expression, error) {
+	return IsErrorObject(args[0]), nil
+}
+
+func expressionToErrorObject(exp Expression) (*ErrorObject, error) {
+	e, ok := exp.(*ErrorObject)
+	if !ok {
+		return nil, fmt.Errorf("%v is not an error object", valueToString(exp))
+	}
+	return e, nil
+}
+
+func errorObjectMessageFunc(args ...Expression) (Expression, error) {
+	e, err := expressionToErrorObject(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	return String(e.message), nil
+}
+
+func errorObjectIrritantsFunc(args ...Expression) (Expression, error) {
+	e, err := expressionToErrorObject(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	return listImpl(e.irritants...)
+}
+
+// evalGuard evaluates the body with the handler escaping to the guard clauses, the condition is raised again by
+// raise-continuable in the dynamic environment of the original raise if no clause matches.
+// (guard (e ((string? e) 'string) (else 'other)) body ...)
+func evalGuard(m *machine, args []Expression, env *Env) error {
+	if len(args) < 2 {
+		return errors.New("guard: syntax error (requires the clauses and body)")
+	}
+	spec, ok := args[0].([]Expression)
+	if !ok || len(spec) < 1 {
+		return errors.New("guard: syntax error (requires variable and clauses)")
+	}
+	sym, err := transExpressionToSymbol(spec[0])
+	if err != nil {
+		return err
+	}
+	outer := m.handlers
+	guardK, guardWinders, guardRun := m.k, m.winders, m
+	handler := NewControlFunction("guard", func(m *machine, args ...Expression) error {
+		condition := args[0]
+		// the raise point returns the value of the outer handlers to the original raise
+		raisePoint := &Continuation{k: &continuation{next: m.k, frame: func(m *machine, _ Expression) error {
+			return m.raise(condition, true)
+		}}, winders: m.winders, handlers: m.handlers, run: m}
+		clauses := &Continuation{k: &continuation{next: guardK, frame: func(m *machine, condition Expression) error {
+			clauseEnv := &Env{outer: env, frame: make(map[Symbol]Expression)}
+			clauseEnv.Set(sym, condition)
+			return m.evalCondClauses(spec[1:], clauseEnv, func(m *machine) error {
+				return raisePoint.resume(m, []Expression{condition})
+			})
+		}}, winders: guardWinders, handlers: outer, run: guardRun}
+		return clauses.resume(m, args)
+	}, 1, 1)
+	m.handlers = &exceptionHandler{handler: handler, parent: outer}
+	m.push(func(m *machine, value Expression) error {
+		m.handlers = outer
+		m.ret(value)
+		return nil
+	})
+	m.evalBody(args[1:], &Env{outer: env, frame: make(map[Symbol]Expression)})
+	return nil
+}
@@ -0,0 +1,101 @@
+package goscheme
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGuard(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{`(guard (e (#t (error-object-message e))) (error "boom" 1 2))`, `"boom"`},
+		{`(guard (e (#t (error-object-irritants e))) (error "boom" 1 'a))`, "(1 a)"},
+		{`(guard (e ((error-object? e))) (error "boom"))`, "#t"},
+		{"(guard (e ((equal? e 'oops) 'caught)) (raise 'oops))", "caught"},
+		{"(guard (e ((string? e) 'string) (else (list 'other e))) (raise 42))", "(other 42)"},
+		{"(guard (e ((car e) => (lambda (x) (* x 2)))) (raise (list 21)))", "42"},
+		{"(guard (e ((number? e) 'outer)) (guard (e ((string? e) 'inner)) (raise 1)))", "outer"},
+		{"(guard (e (#t 'caught)) 1 2)", "2"},
+		{"(guard (e ((error-object? e) (error-object-message e))) undefined-variable)", `"symbol undefined-variable unbound"`},
+		{"(guard (e ((error-object? e) (error-object-message e))) (car 1 2))", `"car requires 1 arguments but 2 arguments provided"`},
+		{"(define trace '()) (define (add x) (set! trace (cons x trace)))" +
+			"(guard (e (#t (add 'handled)))" +
+			" (dynamic-wind (lambda () (add 'in)) (lambda () (raise 'oops)) (lambda () (add 'out)))) trace",
+			"(handled out in)"},
+		{"(define h (make-hash-table)) (hash-table-set! h 'a 1)" +
+			"(guard (e ((error-object? e) (error-object-irritants e))) (hash-table-walk h (lambda (k v) (error \"bad\" k v))))",
+			"(a 1)"},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+}
+
+func TestExceptionHandler(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"(with-exception-handler (lambda (e) 42) (lambda () (+ (raise-continuable 'oops) 1)))", "43"},
+		{"(with-exception-handler (lambda (e) 1) (lambda () 'normal))", "normal"},
+		{"(call/cc (lambda (k) (with-exception-handler (lambda (e) (k (list 'handled e))) (lambda () (raise 'boom)))))",
+			"(handled boom)"},
+		{"(call/cc (lambda (k) (with-exception-handler (lambda (e) (k (error-object-message e))) (lambda () (vector-ref (vector) 0)))))",
+			`"vector-ref: index 0 out of range"`},
+		{"(with-exception-handler (lambda (e) (* e 2))" +
+			" (lambda () (with-exception-handler (lambda (e) (raise-continuable (+ e 1))) (lambda () (raise-continuable 1)))))", "4"},
+		{`(error-object? (guard (e (#t e)) (error "x")))`, "#t"},
+		{"(error-object? 'x)", "#f"},
+		{`(guard (e (#t e)) (error "msg" 1 "two"))`, `#<error "msg" 1 "two">`},
+		// the handlers are active in the callbacks of builtins and the unquoted expressions
+		{"(with-exception-handler (lambda (e) 5) (lambda () `(1 ,(raise-continuable 'x))))", "(1 5)"},
+		{"(with-exception-handler (lambda (e) (list e)) (lambda () `(1 ,@(raise-continuable 'x))))", "(1 x)"},
+		{"(define h (make-hash-table)) (hash-table-set! h 'a 1) (hash-table-set! h 'b 2)" +
+			"(with-exception-handler (lambda (e) 10)" +
+			" (lambda () (let ((s 0)) (hash-table-walk h (lambda (k v) (set! s (+ s v (raise-continuable k))))) s)))", "23"},
+		{"(with-exception-handler (lambda (e) 7) (lambda () (hash-table-ref h 'none (lambda () (+ 1 (raise-continuable 'missing))))))", "8"},
+		{"(with-exception-handler (lambda (e) #t) (lambda () (member 2 '(1 2 3) (lambda (a b) (raise-continuable b)))))", "(1 2 3)"},
+		{"(with-exception-handler (lambda (e) 3) (lambda () (force (delay (* 2 (raise-continuable 'p))))))", "6"},
+		{"(guard (e (#t (list 'caught e))) (hash-table-update! h 'a (lambda (v) (raise 'inner))))", "(caught inner)"},
+		// the condition unmatched by guard is raised again in the dynamic environment of the original raise
+		{"(with-exception-handler (lambda (e) 10) (lambda () (guard (e2 (#f 2)) (+ 1 (raise-continuable 7)))))", "11"},
+		{"(guard (e (#t (list 'outer e))) (guard (e2 ((string? e2) 'inner)) (raise 'sym)))", "(outer sym)"},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+
+	errorCases := []struct {
+		input   string
+		message string
+	}{
+		{"(raise 'boom)", "uncaught exception: boom"},
+		{`(error "boom" 1 "two")`, `boom 1 "two"`},
+		{"(guard (e ((string? e) 'string)) (car 1 2))", "car requires 1 arguments but 2 arguments provided"},
+		{"(with-exception-handler (lambda (e) 'ignored) (lambda () (raise 'boom)))",
+			"exception handler returned from non-continuable raise boom"},
+		{"(with-exception-handler (lambda (e) 1) (lambda () 2)) (raise 'after)", "uncaught exception: after"},
+		{"(error 1)", "error: 1 is not a string"},
+		{"(error-object-message 'x)", "x is not an error object"},
+		{"(guard (e) (raise 1))", "uncaught exception: 1"},
+		// the handler returning to the non-continuable raise through guard raises the secondary exception
+		{"(with-exception-handler (lambda (e) (if (eqv? e 7) 10 (raise e))) (lambda () (guard (e2 (#f 2)) (+ 1 (raise 7)))))",
+			"exception handler returned from non-continuable raise 7"},
+		{"(with-exception-handler (lambda (e) (if (eqv? e 7) 10 (raise e))) (lambda () (+ 1 (raise 7))))",
+			"exception handler returned from non-continuable raise 7"},
+	}
+	for _, c := range errorCases {
+		_, err := EvalAll(strToToken(c.input), env)
+		if assert.NotNil(t, err, c.input) {
+			assert.Equal(t, c.message, err.Error(), c.input)
+		}
+	}
+}
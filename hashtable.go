@@ -0,0 +1,426 @@
+package goscheme
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// hashComparator decides which keys of a HashTable are the same.
+type hashComparator int
+
+const (
+	// equalComparator compares the keys structurally like equal?.
+	equalComparator hashComparator = iota
+	// eqvComparator compares the keys by identity, numbers and characters by value like eqv?.
+	eqvComparator
+	// stringComparator only accepts string keys and compares them by content like string=?.
+	stringComparator
+)
+
+var hashComparatorNames = map[hashComparator]string{
+	equalComparator:  "equal",
+	eqvComparator:    "eqv",
+	stringComparator: "string",
+}
+
+// hashComparators maps the comparator arguments of make-hash-table, either the symbols or the names of the builtin
+// equivalence functions, to the comparators.
+var hashComparators = map[string]hashComparator{
+	"equal":    equalComparator,
+	"equal?":   equalComparator,
+	"eqv":      eqvComparator,
+	"eqv?":     eqvComparator,
+	"eq":       eqvComparator,
+	"eq?":      eqvComparator,
+	"string":   stringComparator,
+	"string=?": stringComparator,
+}
+
+// hashEntry holds the original key and value of an entry, index records the insertion order.
+type hashEntry struct {
+	key, value Expression
+	index      int
+}
+
+// HashTable maps keys to values in constant time. Should only use with pointer.
+type HashTable struct {
+	comparator hashComparator
+	// entries groups the entries by the hash keys, the keys of the structural hash keys are only truncated
+	// representations so the entries sharing them are told apart by equal?.
+	entries map[Expression][]*hashEntry
+	size    int
+	count   int
+}
+
+// NewHashTable constructs an empty *HashTable comparing keys with equal?.
+func NewHashTable() *HashTable {
+	return &HashTable{comparator: equalComparator, entries: make(map[Expression][]*hashEntry)}
+}
+
+// String returns the string representing the *HashTable.
+func (h *HashTable) String() string {
+	return fmt.Sprintf("#[HashTable %s]", hashComparatorNames[h.comparator])
+}
+
+// structuralKey is the hash key of the compound values compared by equal?.
+type structuralKey string
+
+// floatKey is the hash key of the inexact numbers, they are identified by the bit patterns like eqv? so NaN keys can
+// be found.
+type floatKey [2]uint64
+
+// structuralKeyLimit is the maximum number of the values written in a structural key, which bounds the keys of large
+// and circular values.
+const structuralKeyLimit = 64
+
+// hashKey returns the comparable go value identifying the key under the comparator of the table.
+func (h *HashTable) hashKey(key Expression) (Expression, error) {
+	if h.comparator == stringComparator {
+		s, err := expressionToGoString(key)
+		if err != nil {
+			return nil, fmt.Errorf("hash table: %s", err)
+		}
+		return s, nil
+	}
+	if IsNullExp(key) {
+		return NilObj, nil
+	}
+	switch k := key.(type) {
+	case Real:
+		return floatKey{math.Float64bits(float64(k))}, nil
+	case Complex:
+		return floatKey{math.Float64bits(real(k)), math.Float64bits(imag(k))}, nil
+	case *BigInt, *Rational:
+		return structuralKey(fmt.Sprintf("%T:%v", k, k)), nil
+	case *MutableString:
+		if h.comparator == equalComparator {
+			return String(k.runes), nil
+		}
+	case *Pair, *Vector, *Bytevector, *Record:
+		if h.comparator == equalComparator {
+			var buf strings.Builder
+			limit := structuralKeyLimit
+			writeStructuralKey(&buf, k, &limit)
+			return structuralKey(buf.String()), nil
+		}
+	}
+	if !reflect.TypeOf(key).Comparable() {
+		return nil, fmt.Errorf("hash table: %v cannot be used as a key", valueToString(key))
+	}
+	return key, nil
+}
+
+// writeStructuralKey writes the representation of exp that is identical for the values equal? to each other. At most
+// limit values are written in depth-first order, so the circular values are written in a finite key.
+func writeStructuralKey(buf *strings.Builder, exp Expression, limit *int) {
+	if *limit <= 0 {
+		buf.WriteString("...")
+		return
+	}
+	*limit--
+	if IsNullExp(exp) {
+		buf.WriteString("()")
+		return
+	}
+	switch v := exp.(type) {
+	case *Pair:
+		buf.WriteString("(")
+		writeStructuralKey(buf, v.Car, limit)
+		buf.WriteString(" . ")
+		writeStructuralKey(buf, v.Cdr, limit)
+		buf.WriteString(")")
+	case *Vector:
+		buf.WriteString("#(")
+		for _, e := range v.Elements {
+			writeStructuralKey(buf, e, limit)
+			buf.WriteString(" ")
+		}
+		buf.WriteString(")")
+	case String:
+		fmt.Fprintf(buf, "%q", string(v))
+	case *MutableString:
+		fmt.Fprintf(buf, "%q", string(v.runes))
+	case Symbol:
+		fmt.Fprintf(buf, "Symbol:%q", string(v))
+	case *Bytevector:
+		buf.WriteString(v.String())
+	case *Record:
+		fmt.Fprintf(buf, "#<%p", v.recordType)
+		for _, e := range v.values {
+			buf.WriteString(" ")
+			writeStructuralKey(buf, e, limit)
+		}
+		buf.WriteString(">")
+	case Real:
+		fmt.Fprintf(buf, "Real:%x", math.Float64bits(float64(v)))
+	case Complex:
+		fmt.Fprintf(buf, "Complex:%x:%x", math.Float64bits(real(v)), math.Float64bits(imag(v)))
+	case *BigInt, *Rational:
+		fmt.Fprintf(buf, "%T:%v", v, v)
+	default:
+		if reflect.ValueOf(exp).Kind() == reflect.Ptr {
+			// other pointers are only equal to themselves
+			fmt.Fprintf(buf, "%T:%p", v, v)
+		} else {
+			fmt.Fprintf(buf, "%T:%v", v, v)
+		}
+	}
+}
+
+// find returns the hash key of key and the position of its entry in the bucket, the position is -1 when the key is
+// not found.
+func (h *HashTable) find(key Expression) (Expression, int, error) {
+	k, err := h.hashKey(key)
+	if err != nil {
+		return nil, -1, err
+	}
+	_, structural := k.(structuralKey)
+	for i, entry := range h.entries[k] {
+		if !structural || isEqual(entry.key, key) {
+			return k, i, nil
+		}
+	}
+	return k, -1, nil
+}
+
+// Get returns the value associated with key.
+func (h *HashTable) Get(key Expression) (Expression, bool, error) {
+	k, i, err := h.find(key)
+	if err != nil || i < 0 {
+		return nil, false, err
+	}
+	return h.entries[k][i].value, true, nil
+}
+
+// Set associates value with key.
+func (h *HashTable) Set(key, value Expression) error {
+	k, i, err := h.find(key)
+	if err != nil {
+		return err
+	}
+	if i >= 0 {
+		h.entries[k][i].value = value
+		return nil
+	}
+	h.entries[k] = append(h.entries[k], &hashEntry{key: key, value: value, index: h.count})
+	h.count++
+	h.size++
+	return nil
+}
+
+// Delete removes the association of key.
+func (h *HashTable) Delete(key Expression) error {
+	k, i, err := h.find(key)
+	if err != nil || i < 0 {
+		return err
+	}
+	bucket := h.entries[k]
+	if len(bucket) == 1 {
+		delete(h.entries, k)
+	} else {
+		h.entries[k] = append(bucket[:i:i], bucket[i+1:]...)
+	}
+	h.size--
+	return nil
+}
+
+// sortedEntries returns the entries in insertion order.
+func (h *HashTable) sortedEntries() []*hashEntry {
+	ret := make([]*hashEntry, 0, h.size)
+	for _, bucket := range h.entries {
+		ret = append(ret, bucket...)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].index < ret[j].index })
+	return ret
+}
+
+// IsHashTable checks whether the expression value is a *HashTable.
+func IsHashTable(exp Expression) bool {
+	_, ok := exp.(*HashTable)
+	return ok
+}
+
+func expressionToHashTable(exp Expression) (*HashTable, error) {
+	h, ok := exp.(*HashTable)
+	if !ok {
+		return nil, fmt.Errorf("%v is not a hash table", valueToString(exp))
+	}
+	return h, nil
+}
+
+func isHashTableFunc(args ...Expression) (Expression, error) {
+	return IsHashTable(args[0]), nil
+}
+
+func makeHashTableFunc(args ...Expression) (Expression, error) {
+	h := NewHashTable()
+	if len(args) == 0 {
+		return h, nil
+	}
+	var name string
+	switch v := args[0].(type) {
+	case Symbol:
+		name = string(v)
+	case Function:
+		name = v.name
+	}
+	comparator, ok := hashComparators[name]
+	if !ok {
+		return UndefObj, fmt.Errorf("make-hash-table: %v is not a valid comparator", valueToString(args[0]))
+	}
+	h.comparator = comparator
+	return h, nil
+}
+
+// lookup passes the value of the key to then, the failure thunk is called for the value when the key is not found.
+func (h *HashTable) lookup(m *machine, key, failure Expression, then frame) error {
+	value, ok, err := h.Get(key)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return then(m, value)
+	}
+	if failure == nil {
+		return fmt.Errorf("hash-table-ref: key %v not found", valueToString(key))
+	}
+	m.push(then)
+	return m.apply(failure, nil)
+}
+
+// update calls the procedure with the value and associates the result with the key.
+func (h *HashTable) update(m *machine, key, procedure, value Expression) error {
+	m.push(func(m *machine, value Expression) error {
+		m.ret(UndefObj)
+		return h.Set(key, value)
+	})
+	return m.apply(procedure, []Expression{value})
+}
+
+// optionalArg returns the argument at index, or nil when it is not provided.
+func optionalArg(args []Expression, index int) Expression {
+	if index < len(args) {
+		return args[index]
+	}
+	return nil
+}
+
+func hashTableRef(m *machine, args ...Expression) error {
+	h, err := expressionToHashTable(args[0])
+	if err != nil {
+		return err
+	}
+	return h.lookup(m, args[1], optionalArg(args, 2), func(m *machine, value Expression) error {
+		m.ret(value)
+		return nil
+	})
+}
+
+func hashTableRefDefaultFunc(args ...Expression) (Expression, error) {
+	h, err := expressionToHashTable(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	value, ok, err := h.Get(args[1])
+	if err != nil {
+		return UndefObj, err
+	}
+	if !ok {
+		return args[2], nil
+	}
+	return value, nil
+}
+
+func hashTableSetFunc(args ...Expression) (Expression, error) {
+	h, err := expressionToHashTable(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	return UndefObj, h.Set(args[1], args[2])
+}
+
+func hashTableDeleteFunc(args ...Expression) (Expression, error) {
+	h, err := expressionToHashTable(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	return UndefObj, h.Delete(args[1])
+}
+
+func hashTableContainsFunc(args ...Expression) (Expression, error) {
+	h, err := expressionToHashTable(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	_, ok, err := h.Get(args[1])
+	return ok, err
+}
+
+func hashTableSizeFunc(args ...Expression) (Expression, error) {
+	h, err := expressionToHashTable(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	return Integer(h.size), nil
+}
+
+// hashTableLister returns the builtin function listing the entries of the hash table converted by the convert function.
+func hashTableLister(convert func(entry *hashEntry) Expression) commonFunction {
+	return func(args ...Expression) (Expression, error) {
+		h, err := expressionToHashTable(args[0])
+		if err != nil {
+			return UndefObj, err
+		}
+		var ret []Expression
+		for _, entry := range h.sortedEntries() {
+			ret = append(ret, convert(entry))
+		}
+		return listImpl(ret...)
+	}
+}
+
+// hashTableWalk calls the procedure with the key and value of each entry in insertion order.
+func hashTableWalk(m *machine, args ...Expression) error {
+	h, err := expressionToHashTable(args[0])
+	if err != nil {
+		return err
+	}
+	entries := h.sortedEntries()
+	var next func(m *machine, i int) error
+	next = func(m *machine, i int) error {
+		if i == len(entries) {
+			m.ret(UndefObj)
+			return nil
+		}
+		m.push(func(m *machine, _ Expression) error {
+			return next(m, i+1)
+		})
+		return m.apply(args[1], []Expression{entries[i].key, entries[i].value})
+	}
+	return next(m, 0)
+}
+
+func hashTableUpdate(m *machine, args ...Expression) error {
+	h, err := expressionToHashTable(args[0])
+	if err != nil {
+		return err
+	}
+	return h.lookup(m, args[1], optionalArg(args, 3), func(m *machine, value Expression) error {
+		return h.update(m, args[1], args[2], value)
+	})
+}
+
+func hashTableUpdateDefault(m *machine, args ...Expression) error {
+	h, err := expressionToHashTable(args[0])
+	if err != nil {
+		return err
+	}
+	value, err := hashTableRefDefaultFunc(h, args[1], args[3])
+	if err != nil {
+		return err
+	}
+	return h.update(m, args[1], args[2], value)
+}
@@ -0,0 +1,76 @@
+package goscheme
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestHashTable(t *testing.T) {
+	h := NewHashTable()
+	assert.Nil(t, h.Set(&Pair{Integer(1), &Pair{Integer(2), NilObj}}, String("a")))
+	value, ok, err := h.Get(&Pair{Integer(1), &Pair{Integer(2), NilObj}})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, String("a"), value)
+	_, ok, _ = h.Get(&Pair{Integer(1), &Pair{Real(2), NilObj}})
+	assert.False(t, ok)
+	assert.Nil(t, h.Delete(&Pair{Integer(1), &Pair{Integer(2), NilObj}}))
+	_, ok, _ = h.Get(&Pair{Integer(1), &Pair{Integer(2), NilObj}})
+	assert.False(t, ok)
+	assert.Equal(t, "#[HashTable equal]", h.String())
+}
+
+func TestHashTableFunctions(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"(define h (make-hash-table)) (hash-table-set! h 'a 1) (hash-table-ref h 'a)", "1"},
+		{"(hash-table? h)", "#t"},
+		{"(hash-table? '())", "#f"},
+		{"(hash-table-ref h 'b (lambda () 'missing))", "missing"},
+		{"(hash-table-ref/default h 'b 0)", "0"},
+		{"(hash-table-set! h '(1 (2 \"x\")) 'list) (hash-table-ref h (list 1 (list 2 \"x\")))", "list"},
+		{"(hash-table-set! h \"key\" 'str) (hash-table-ref h (string-copy \"key\"))", "str"},
+		{"(hash-table-set! h #(1 2) 'vec) (hash-table-ref h (vector 1 2))", "vec"},
+		{"(hash-table-set! h 9223372036854775808 'big) (hash-table-ref h (+ 9223372036854775807 1))", "big"},
+		{"(hash-table-contains? h 1.0)", "#f"},
+		{"(hash-table-set! h 1 'one) (hash-table-contains? h 1)", "#t"},
+		{"(hash-table-delete! h 1) (hash-table-contains? h 1)", "#f"},
+		{"(hash-table-size h)", "5"},
+		{"(hash-table-keys h)", `(a (1 (2 "x")) "key" #(1 2) 9223372036854775808)`},
+		{"(hash-table-values h)", "(1 list str vec big)"},
+		{"(hash-table-set! h 'a 2) (hash-table->alist h)", `((a . 2) ((1 (2 "x")) . list) ("key" . str) (#(1 2) . vec) (9223372036854775808 . big))`},
+		{"(hash-table-update! h 'a (lambda (x) (* x 10))) (hash-table-ref h 'a)", "20"},
+		{"(hash-table-update! h 'c (lambda (x) (+ x 1)) (lambda () 0)) (hash-table-ref h 'c)", "1"},
+		{"(hash-table-update!/default h 'd (lambda (x) (+ x 1)) 10) (hash-table-ref h 'd)", "11"},
+		{"(define e (make-hash-table 'eqv)) (hash-table-set! e (list 1) 1) (hash-table-contains? e (list 1))", "#f"},
+		{"(define l (list 1)) (hash-table-set! e l 1) (hash-table-contains? e l)", "#t"},
+		{"(define s (make-hash-table string=?)) (hash-table-set! s \"a\" 1) (hash-table-ref s (string-copy \"a\"))", "1"},
+		{"s", "#[HashTable string]"},
+		{"(define sum 0) (hash-table-walk h (lambda (k v) (if (number? v) (set! sum (+ sum v))))) sum", "32"},
+		// circular keys, keys sharing the truncated structural key, gensyms and NaN
+		{"(define c (make-hash-table)) (define l1 (list 1 2)) (set-cdr! (cdr l1) l1) (define l2 (list 1 2 1 2))" +
+			" (set-cdr! (cdr (cdr (cdr l2))) l2) (hash-table-set! c l1 'circular) (hash-table-ref c l2)", "circular"},
+		{"(define (zeros n) (if (= n 0) '() (cons 0 (zeros (- n 1)))))" +
+			" (hash-table-set! c (zeros 100) 'short) (hash-table-set! c (append (zeros 100) '(1)) 'long)" +
+			" (list (hash-table-ref c (zeros 100)) (hash-table-ref c (append (zeros 100) '(1))) (hash-table-size c))",
+			"(short long 3)"},
+		{"(hash-table-delete! c (zeros 100)) (list (hash-table-contains? c (zeros 100)) (hash-table-size c))", "(#f 2)"},
+		{"(hash-table-set! c (list (gensym 'a)) 'gensym) (hash-table-contains? c (list 'a))", "#f"},
+		{"(hash-table-set! c (/ 0. 0.) 'nan) (list (hash-table-ref c (/ 0. 0.)) (hash-table-contains? c (list (/ 0. 0.))))", "(nan #f)"},
+		{"(hash-table-set! c (list (/ 0. 0.)) 'nan-list) (hash-table-ref c (list (/ 0. 0.)))", "nan-list"},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+
+	for _, input := range []string{"(hash-table-ref h 'missing)", "(make-hash-table 'unknown)", "(hash-table-set! s 'a 1)",
+		"(hash-table-update! h 'missing (lambda (x) x))", "(hash-table-ref '() 'a)", "(hash-table-set! h car 1)"} {
+		_, err := EvalAll(strToToken(input), env)
+		assert.NotNil(t, err, input)
+	}
+}
@@ -0,0 +1,337 @@
+package goscheme
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Keyword is the self-evaluating keyword such as name: used to pass the keyword arguments. The reader produces symbols
+// for name:, they are only read as keywords in the operands of the calls to the procedures with #!key parameters.
+type Keyword string
+
+// String returns the keyword with the trailing colon.
+func (k Keyword) String() string {
+	return string(k) + ":"
+}
+
+// isKeywordToken checks whether the token is a keyword, a symbol ending with colon such as name:.
+func isKeywordToken(token string) bool {
+	return len(token) > 1 && strings.HasSuffix(token, ":") && strings.Trim(token, ":") != "" &&
+		!strings.HasPrefix(token, "\"") && !strings.HasPrefix(token, "#")
+}
+
+// toKeyword converts the Keyword or the symbol ending with colon to Keyword.
+func toKeyword(exp Expression) (Keyword, bool) {
+	switch v := exp.(type) {
+	case Keyword:
+		return v, true
+	case Symbol:
+		if isKeywordToken(string(v)) {
+			return Keyword(strings.TrimSuffix(string(v), ":")), true
+		}
+	}
+	return "", false
+}
+
+// keywordOperands returns the operands of the call to the procedure, the symbols ending with colon are the keywords
+// instead of variables when the procedure has #!key parameters.
+func keywordOperands(procedure Expression, operands []Expression) []Expression {
+	p, ok := procedure.(*LambdaProcess)
+	if !ok || len(p.keys) == 0 {
+		return operands
+	}
+	ret := make([]Expression, len(operands))
+	for i, operand := range operands {
+		if k, ok := toKeyword(operand); ok {
+			ret[i] = k
+		} else {
+			ret[i] = operand
+		}
+	}
+	return ret
+}
+
+// IsKeywordObject checks whether the expression value is a Keyword.
+func IsKeywordObject(exp Expression) bool {
+	_, ok := exp.(Keyword)
+	return ok
+}
+
+// defaultParam is the optional or keyword parameter, init is the expression of the default value, nil means #f.
+type defaultParam struct {
+	name Symbol
+	init Expression
+}
+
+// formals is the parameter list of lambda: the required parameters, the optional parameters after #!optional, the
+// keyword parameters after #!key and the rest parameter after #!rest or the dot.
+type formals struct {
+	params    []Symbol
+	optionals []defaultParam
+	keys      []defaultParam
+	rest      Symbol
+}
+
+// String returns the parameter list such as (a b . rest), args and (a #!optional (b 1) #!key c).
+func (f formals) String() string {
+	if len(f.params) == 0 && len(f.optionals) == 0 && len(f.keys) == 0 && f.rest != "" {
+		return string(f.rest)
+	}
+	var parts []string
+	for _, p := range f.params {
+		parts = append(parts, string(p))
+	}
+	writeDefaults := func(marker string, params []defaultParam) {
+		if len(params) == 0 {
+			return
+		}
+		parts = append(parts, marker)
+		for _, p := range params {
+			if p.init == nil {
+				parts = append(parts, string(p.name))
+			} else {
+				parts = append(parts, fmt.Sprintf("(%s %s)", p.name, expToPrintString(p.init)))
+			}
+		}
+	}
+	writeDefaults("#!optional", f.optionals)
+	writeDefaults("#!key", f.keys)
+	if f.rest != "" {
+		if len(f.optionals) == 0 && len(f.keys) == 0 {
+			parts = append(parts, ".", string(f.rest))
+		} else {
+			parts = append(parts, "#!rest", string(f.rest))
+		}
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+// accepts checks whether the procedure with the parameters can be called with count arguments.
+func (f formals) accepts(count int) bool {
+	if count < len(f.params) {
+		return false
+	}
+	return f.rest != "" || len(f.keys) > 0 || count <= len(f.params)+len(f.optionals)
+}
+
+// parseLambdaList reads the parameter list of lambda, a single symbol takes all the arguments as a list.
+func parseLambdaList(spec Expression) (formals, error) {
+	var f formals
+	list, ok := spec.([]Expression)
+	if !ok {
+		rest, err := transExpressionToSymbol(spec)
+		f.rest = rest
+		return f, err
+	}
+	if n := len(list); n >= 2 && isDot(list[n-2]) {
+		rest, err := transExpressionToSymbol(list[n-1])
+		if err != nil {
+			return f, err
+		}
+		f.rest, list = rest, list[:n-2]
+	}
+	section := ""
+	for i := 0; i < len(list); i++ {
+		e := list[i]
+		switch {
+		case isDot(e):
+			return f, errors.New("syntax error: misplaced dot in parameters")
+		case isKeyword(e, "#!optional"), isKeyword(e, "#!key"):
+			section = symbolName(e)
+		case isKeyword(e, "#!rest"):
+			if f.rest != "" || i+1 >= len(list) {
+				return f, errors.New("syntax error: #!rest requires exactly one parameter")
+			}
+			rest, err := transExpressionToSymbol(list[i+1])
+			if err != nil {
+				return f, err
+			}
+			f.rest = rest
+			i++
+		case section == "":
+			sym, err := transExpressionToSymbol(e)
+			if err != nil {
+				return f, err
+			}
+			f.params = append(f.params, sym)
+		default:
+			param, err := parseDefaultParam(e)
+			if err != nil {
+				return f, err
+			}
+			if section == "#!optional" {
+				f.optionals = append(f.optionals, param)
+			} else {
+				f.keys = append(f.keys, param)
+			}
+		}
+	}
+	return f, nil
+}
+
+// parseDefaultParam reads the parameter name or (name default).
+func parseDefaultParam(exp Expression) (defaultParam, error) {
+	var init Expression
+	if spec, ok := exp.([]Expression); ok {
+		if len(spec) != 2 {
+			return defaultParam{}, fmt.Errorf("syntax error: invalid parameter %v", expToPrintString(exp))
+		}
+		exp, init = spec[0], spec[1]
+	}
+	name, err := transExpressionToSymbol(exp)
+	if err != nil {
+		return defaultParam{}, err
+	}
+	return defaultParam{name: name, init: init}, nil
+}
+
+// keywordArguments reads the keyword and value pairs, the unknown keywords are only allowed with the rest parameter.
+func (f formals) keywordArguments(args []Expression) (map[Symbol]Expression, error) {
+	if len(args)%2 != 0 {
+		return nil, errors.New("keyword arguments must be pairs of keyword and value")
+	}
+	ret := make(map[Symbol]Expression)
+	for i := 0; i < len(args); i += 2 {
+		k, ok := toKeyword(args[i])
+		if !ok {
+			return nil, fmt.Errorf("%v is not a keyword", valueToString(args[i]))
+		}
+		if !f.hasKey(Symbol(k)) && f.rest == "" {
+			return nil, fmt.Errorf("unknown keyword argument %v", k)
+		}
+		ret[Symbol(k)] = args[i+1]
+	}
+	return ret, nil
+}
+
+func (f formals) hasKey(name Symbol) bool {
+	for _, p := range f.keys {
+		if p.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// bindLambda binds the arguments to the parameters of the lambda, the default values of the missing optional and
+// keyword arguments are evaluated in order in the scope of the preceding parameters.
+func (m *machine) bindLambda(p *LambdaProcess, args []Expression, then func(m *machine, env *Env) error) error {
+	if len(p.optionals) == 0 && len(p.keys) == 0 {
+		newEnv, err := p.bind(args)
+		if err != nil {
+			return err
+		}
+		return then(m, newEnv)
+	}
+	if !p.accepts(len(args)) {
+		return p.arityError(len(args))
+	}
+	newEnv := &Env{outer: p.env, frame: make(map[Symbol]Expression)}
+	for i, param := range p.params {
+		newEnv.Set(param, args[i])
+	}
+	rest := args[len(p.params):]
+	var missing []defaultParam
+	for i, param := range p.optionals {
+		if i < len(rest) {
+			newEnv.Set(param.name, rest[i])
+		} else {
+			missing = append(missing, param)
+		}
+	}
+	if len(rest) > len(p.optionals) {
+		rest = rest[len(p.optionals):]
+	} else {
+		rest = nil
+	}
+	if len(p.keys) > 0 {
+		provided, err := p.keywordArguments(rest)
+		if err != nil {
+			return err
+		}
+		for _, param := range p.keys {
+			if v, ok := provided[param.name]; ok {
+				newEnv.Set(param.name, v)
+			} else {
+				missing = append(missing, param)
+			}
+		}
+	}
+	if p.rest != "" {
+		list, _ := listImpl(rest...)
+		newEnv.Set(p.rest, list)
+	}
+	return m.bindDefaults(newEnv, missing, then)
+}
+
+func (m *machine) bindDefaults(env *Env, params []defaultParam, then func(m *machine, env *Env) error) error {
+	if len(params) == 0 {
+		return then(m, env)
+	}
+	param := params[0]
+	if param.init == nil {
+		env.Set(param.name, false)
+		return m.bindDefaults(env, params[1:], then)
+	}
+	m.evalThen(param.init, env, func(m *machine, value Expression) error {
+		env.Set(param.name, value)
+		return m.bindDefaults(env, params[1:], then)
+	})
+	return nil
+}
+
+// CaseLambda is the procedure created by case-lambda, it calls the first clause accepting the count of arguments.
+// Should only use with pointer.
+type CaseLambda struct {
+	clauses []*LambdaProcess
+}
+
+// String returns the string representing the *CaseLambda.
+func (c *CaseLambda) String() string {
+	var buf bytes.Buffer
+	buf.WriteString("(case-lambda")
+	for _, clause := range c.clauses {
+		buf.WriteString(" (")
+		buf.WriteString(clause.formals.String())
+		buf.WriteString(" ")
+		buf.WriteString(concatLambdaBodyToString(clause.body))
+		buf.WriteString(")")
+	}
+	buf.WriteString(")")
+	return buf.String()
+}
+
+// IsCaseLambda checks whether the expression value is a *CaseLambda.
+func IsCaseLambda(exp Expression) bool {
+	_, ok := exp.(*CaseLambda)
+	return ok
+}
+
+// clause returns the first clause accepting count arguments.
+func (c *CaseLambda) clause(count int) (*LambdaProcess, error) {
+	for _, clause := range c.clauses {
+		if clause.accepts(count) {
+			return clause, nil
+		}
+	}
+	return nil, fmt.Errorf("case-lambda: no clause accepts %d arguments", count)
+}
+
+// evalCaseLambda creates the *CaseLambda, (case-lambda ((x) x) ((x y) (+ x y)) ((x . rest) rest)).
+func evalCaseLambda(args []Expression, env *Env) (Expression, error) {
+	c := &CaseLambda{}
+	for _, exp := range args {
+		clause, ok := exp.([]Expression)
+		if !ok || len(clause) < 2 {
+			return UndefObj, fmt.Errorf("case-lambda: invalid clause %v", expToPrintString(exp))
+		}
+		f, err := parseLambdaList(clause[0])
+		if err != nil {
+			return UndefObj, err
+		}
+		c.clauses = append(c.clauses, makeLambdaProcess(f, clause[1:], env))
+	}
+	return c, nil
+}
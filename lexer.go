@@ -3,6 +3,7 @@ package goscheme
 import (
 	"bufio"
 	"io"
+	"strconv"
 	"strings"
 	"unicode"
 )
@@ -19,6 +20,11 @@ type Tokenizer struct {
 	EOF          bool
 	currentCh    rune
 	currentToken string
+	// started is set after the first token, a shebang line is only skipped before it
+	started bool
+	// foldCase is switched by the #!fold-case and #!no-fold-case directives to read the symbols and character names
+	// in lower case
+	foldCase bool
 }
 
 // NewTokenizerFromString construct *Tokenizer from string
@@ -50,14 +56,7 @@ func (t *Tokenizer) readString() (string, bool) {
 	for !t.EOF && t.currentCh != '"' {
 		if t.currentCh == '\\' {
 			t.readAhead()
-			if t.currentCh == 'n' {
-				buf = append(buf, '\n')
-			} else if t.currentCh == 't' {
-				buf = append(buf, '\t')
-			} else {
-				buf = append(buf, t.currentCh)
-			}
-			t.readAhead()
+			buf = append(buf, t.readEscape())
 			continue
 		}
 		buf = append(buf, t.currentCh)
@@ -71,6 +70,57 @@ func (t *Tokenizer) readString() (string, bool) {
 	return string(buf), true
 }
 
+// readBarSymbol reads the symbol written between vertical lines such as |hello world|, the escape sequences are read
+// like strings. The token keeps the vertical lines.
+func (t *Tokenizer) readBarSymbol() (string, bool) {
+	buf := []rune{'|'}
+	t.readAhead()
+	for !t.EOF && t.currentCh != '|' {
+		if t.currentCh == '\\' {
+			t.readAhead()
+			buf = append(buf, t.readEscape())
+			continue
+		}
+		buf = append(buf, t.currentCh)
+		t.readAhead()
+	}
+	if t.EOF {
+		return "", false
+	}
+	buf = append(buf, '|')
+	t.readAhead()
+	return string(buf), true
+}
+
+// stringUnescapes maps the letters of the escape sequences in strings to the characters.
+var stringUnescapes = map[rune]rune{'a': '\a', 'b': '\b', 't': '\t', 'n': '\n', 'r': '\r'}
+
+// readEscape reads the escape sequence after the backslash in a string, such as \n or the hex scalar value \x41;.
+// The other characters are escaped to themselves.
+func (t *Tokenizer) readEscape() rune {
+	ch := t.currentCh
+	t.readAhead()
+	if r, ok := stringUnescapes[ch]; ok {
+		return r
+	}
+	if ch != 'x' {
+		return ch
+	}
+	var digits []rune
+	for ; !t.EOF && t.currentCh != ';' && t.currentCh != '"'; t.readAhead() {
+		digits = append(digits, t.currentCh)
+	}
+	if t.EOF || t.currentCh != ';' {
+		return unicode.ReplacementChar
+	}
+	t.readAhead()
+	n, err := strconv.ParseInt(string(digits), 16, 32)
+	if err != nil {
+		return unicode.ReplacementChar
+	}
+	return rune(n)
+}
+
 func (t *Tokenizer) readSymbol() (string, bool) {
 	buf := make([]rune, 0, 1)
 	if t.EOF {
@@ -80,11 +130,77 @@ func (t *Tokenizer) readSymbol() (string, bool) {
 		buf = append(buf, t.currentCh)
 		t.readAhead()
 	}
+	if t.foldCase {
+		return strings.ToLower(string(buf)), true
+	}
+	return string(buf), true
+}
+
+// readHashPrefixed reads the token starting with '#' such as character literal #\a, boolean #t, number #x1F
+// and the opening of vector literal #( and bytevector literal #u8(. The block comment #| |#, the datum comment #;, the
+// directives #!fold-case and #!no-fold-case and the leading shebang line are skipped.
+func (t *Tokenizer) readHashPrefixed() (string, bool) {
+	t.readAhead()
+	if !t.EOF && t.currentCh == '\\' {
+		return t.readCharacter()
+	}
+	if !t.EOF && t.currentCh == '(' {
+		t.readAhead()
+		return "#(", true
+	}
+	if !t.EOF && t.currentCh == '|' {
+		t.skipBlockComment()
+		return t.readNextToken()
+	}
+	if !t.EOF && t.currentCh == ';' {
+		t.readAhead()
+		t.nextDatumTokens()
+		return t.readNextToken()
+	}
+	isShebang := !t.started && !t.EOF && t.currentCh == '!'
+	rest, _ := t.readSymbol()
+	switch {
+	case rest == "!fold-case":
+		t.foldCase = true
+		return t.readNextToken()
+	case rest == "!no-fold-case":
+		t.foldCase = false
+		return t.readNextToken()
+	case isShebang && (rest == "!" || strings.HasPrefix(rest, "!/")):
+		t.skipLine()
+		return t.readNextToken()
+	}
+	if rest == "u8" && !t.EOF && t.currentCh == '(' {
+		t.readAhead()
+		return "#u8(", true
+	}
+	return "#" + rest, true
+}
+
+// readCharacter reads the character literal after #, the first character after the backslash is always part of the
+// literal even if it is a delimiter like #\( and #\space.
+func (t *Tokenizer) readCharacter() (string, bool) {
+	buf := []rune{'#', '\\'}
+	t.readAhead()
+	if t.EOF {
+		return string(buf), true
+	}
+	first := t.currentCh
+	buf = append(buf, first)
+	t.readAhead()
+	for !t.EOF && isSymbolCh(first) && isSymbolCh(t.currentCh) {
+		buf = append(buf, t.currentCh)
+		t.readAhead()
+	}
+	// a single character such as #\A keeps its case
+	if t.foldCase && len(buf) > 3 {
+		return strings.ToLower(string(buf)), true
+	}
 	return string(buf), true
 }
 
 func isSymbolCh(r rune) bool {
-	return !unicode.IsSpace(r) && !strings.ContainsRune("()'", r)
+	return !unicode.IsSpace(r) && !strings.ContainsRune("()'`,", r)
 }
 
 func (t *Tokenizer) skipComment() {
@@ -99,6 +215,57 @@ func (t *Tokenizer) skipComment() {
 	}
 }
 
+// skipLine skips the rest of the current line.
+func (t *Tokenizer) skipLine() {
+	for !t.EOF && t.currentCh != '\n' {
+		t.readAhead()
+	}
+	t.readAhead()
+}
+
+// skipBlockComment skips the block comment which may be nested such as #| a #| b |# c |#, the current character is the
+// '|' after '#'.
+func (t *Tokenizer) skipBlockComment() {
+	depth := 1
+	var prev rune
+	t.readAhead()
+	for !t.EOF && depth > 0 {
+		ch := t.currentCh
+		if prev == '|' && ch == '#' {
+			depth--
+			ch = 0
+		} else if prev == '#' && ch == '|' {
+			depth++
+			ch = 0
+		}
+		prev = ch
+		t.readAhead()
+	}
+}
+
+// nextDatumTokens reads the tokens of the next datum, the tokens of a list are read until the matching ')' and the
+// prefix tokens such as ' are read with the datum following them. ok is false when the input ends before the datum
+// completes.
+func (t *Tokenizer) nextDatumTokens() (tokens []string, ok bool) {
+	depth := 0
+	for {
+		token, ok := t.NextToken()
+		if !ok {
+			return tokens, false
+		}
+		tokens = append(tokens, token)
+		switch token {
+		case "(", "#(", "#u8(":
+			depth++
+		case ")":
+			depth--
+		}
+		if _, prefix := readerMacros[token]; depth <= 0 && !prefix {
+			return tokens, true
+		}
+	}
+}
+
 func (t *Tokenizer) readNextToken() (string, bool) {
 
 	if t.EOF {
@@ -124,6 +291,9 @@ func (t *Tokenizer) readNextToken() (string, bool) {
 	if t.currentCh == '"' {
 		return t.readString()
 	}
+	if t.currentCh == '#' {
+		return t.readHashPrefixed()
+	}
 	if t.currentCh == '(' {
 		t.readAhead()
 		return "(", true
@@ -132,6 +302,9 @@ func (t *Tokenizer) readNextToken() (string, bool) {
 		t.readAhead()
 		return ")", true
 	}
+	if t.currentCh == '|' {
+		return t.readBarSymbol()
+	}
 	if isSymbolCh(t.currentCh) {
 		return t.readSymbol()
 	}
@@ -139,6 +312,18 @@ func (t *Tokenizer) readNextToken() (string, bool) {
 		t.readAhead()
 		return "'", true
 	}
+	if t.currentCh == '`' {
+		t.readAhead()
+		return "`", true
+	}
+	if t.currentCh == ',' {
+		t.readAhead()
+		if !t.EOF && t.currentCh == '@' {
+			t.readAhead()
+			return ",@", true
+		}
+		return ",", true
+	}
 	return "", false
 }
 
@@ -146,6 +331,7 @@ func (t *Tokenizer) readNextToken() (string, bool) {
 func (t *Tokenizer) NextToken() (string, bool) {
 	token, ok := t.readNextToken()
 	t.currentToken = token
+	t.started = t.started || ok
 	return t.currentToken, ok
 }
 
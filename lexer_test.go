@@ -31,6 +31,26 @@ func TestTokenize(t *testing.T) {
 		{"'x()", []string{"'", "x", "(", ")"}},
 		{"' x", []string{"'", "x"}},
 		{"\"'x\"", []string{`"'x"`}},
+		{`#\a`, []string{`#\a`}},
+		{`(#\( #\))`, []string{"(", `#\(`, `#\)`, ")"}},
+		{`#\ #\space`, []string{`#\ `, `#\space`}},
+		{`#\x41)`, []string{`#\x41`, ")"}},
+		{"#t #xff", []string{"#t", "#xff"}},
+		{"#(1 #(2))", []string{"#(", "1", "#(", "2", ")", ")"}},
+		{"#u8(1 2) #u8", []string{"#u8(", "1", "2", ")", "#u8"}},
+		{"`(a ,b ,@c)", []string{"`", "(", "a", ",", "b", ",@", "c", ")"}},
+		{"`(a,b , @c)", []string{"`", "(", "a", ",", "b", ",", "@c", ")"}},
+		// test comments and directives
+		{"#| a (b |# c", []string{"c"}},
+		{"#| a #| nested |# (|#c", []string{"c"}},
+		{"#| unterminated", nil},
+		{"(a #;(b (c)) d)", []string{"(", "a", "d", ")"}},
+		{"#; #; a b c", []string{"c"}},
+		{"#;'(a) b ; c", []string{"b"}},
+		{"#!fold-case ABC #\\A #\\SPACE \"Str\" #!no-fold-case ABC", []string{"abc", `#\A`, `#\space`, `"Str"`, "ABC"}},
+		{"#!/usr/bin/env goscheme\n(a)", []string{"(", "a", ")"}},
+		{"a #!/usr/bin/env", []string{"a", "#!/usr/bin/env"}},
+		{"(lambda (#!optional b) b)", []string{"(", "lambda", "(", "#!optional", "b", ")", "b", ")"}},
 	}
 	for _, c := range testCases {
 		assert.Equal(t, c.expected, Tokenize(c.input))
@@ -44,18 +64,37 @@ func TestParse(t *testing.T) {
 		err      error
 	}{
 		{[]string{"(", ")"}, []Expression{[]Expression{}}, nil},
-		{[]string{"3"}, []Expression{"3"}, nil},
-		{[]string{"3", "(", "define", "x", "1", ")"}, []Expression{"3", []Expression{"define", "x", "1"}}, nil},
-		{[]string{"3", "(", "define", "x", "1"}, []Expression{"3", []Expression{"define", "x", 1}}, errors.New("syntax error")},
-		{[]string{"(", "define", "x", "3", ")"}, []Expression{[]Expression{"define", "x", "3"}}, nil},
+		{[]string{"3"}, []Expression{Integer(3)}, nil},
+		{[]string{"3", "(", "define", "x", "1", ")"}, []Expression{Integer(3), []Expression{Symbol("define"), Symbol("x"), Integer(1)}}, nil},
+		{[]string{"3", "(", "define", "x", "1"}, []Expression{Integer(3), []Expression{Symbol("define"), Symbol("x"), Integer(1)}}, errors.New("syntax error")},
+		{[]string{"(", "define", "x", "3", ")"}, []Expression{[]Expression{Symbol("define"), Symbol("x"), Integer(3)}}, nil},
 		{[]string{"(", "define", "(", "func", "x", ")", "(", "define", "(", "intern", "x", ")", "(", "x", ")", ")", ")"},
-			[]Expression{[]Expression{"define",
-				[]Expression{"func", "x"}, []Expression{"define", []Expression{"intern", "x"}, []Expression{"x"}}}}, nil},
+			[]Expression{[]Expression{Symbol("define"),
+				[]Expression{Symbol("func"), Symbol("x")}, []Expression{Symbol("define"), []Expression{Symbol("intern"), Symbol("x")}, []Expression{Symbol("x")}}}}, nil},
 		// test quote abbreviation
-		{[]string{"'", "x"}, []Expression{[]Expression{"quote", "x"}}, nil},
-		{[]string{"'", "x", "(", ")"}, []Expression{[]Expression{"quote", "x"}, []Expression{}}, nil},
-		{[]string{"'", "(", "x", ")"}, []Expression{[]Expression{"quote", []Expression{"x"}}}, nil},
-		{[]string{"'", "(", "x", ")"}, []Expression{[]Expression{"quote", []Expression{"x"}}}, nil},
+		{[]string{"'", "x"}, []Expression{[]Expression{Symbol("quote"), Symbol("x")}}, nil},
+		{[]string{"'", "x", "(", ")"}, []Expression{[]Expression{Symbol("quote"), Symbol("x")}, []Expression{}}, nil},
+		{[]string{"'", "(", "x", ")"}, []Expression{[]Expression{Symbol("quote"), []Expression{Symbol("x")}}}, nil},
+		{[]string{"'", "(", "x", ")"}, []Expression{[]Expression{Symbol("quote"), []Expression{Symbol("x")}}}, nil},
+		{[]string{"`", "(", "x", ",", "y", ",@", "z", ")"},
+			[]Expression{[]Expression{Symbol("quasiquote"), []Expression{Symbol("x"), []Expression{Symbol("unquote"), Symbol("y")}, []Expression{Symbol("unquote-splicing"), Symbol("z")}}}}, nil},
+		// test vector literal
+		{[]string{"#(", "1", "(", "x", ")", ")"}, []Expression{vectorLiteral{Integer(1), []Expression{Symbol("x")}}}, nil},
+		// test typed atoms
+		{[]string{`"x"`, "#t", "#false", `#\a`, "key:", "1.5"},
+			[]Expression{String("x"), true, false, Char('a'), Symbol("key:"), Real(1.5)}, nil},
+		{[]string{`"a"b"`, "|x|"}, []Expression{String(`a"b`), Symbol("x")}, nil},
+		{[]string{`#\unknown`}, nil, errors.New("syntax error")},
+		{[]string{"#(", "1"}, nil, errors.New("syntax error")},
+		// test dotted list
+		{[]string{"(", "a", ".", "b", ")"}, []Expression{[]Expression{Symbol("a"), Symbol("."), Symbol("b")}}, nil},
+		{[]string{"'", "(", "(", "a", ".", "1", ")", "b", ".", "(", "c", ")", ")"},
+			[]Expression{[]Expression{Symbol("quote"), []Expression{[]Expression{Symbol("a"), Symbol("."), Integer(1)}, Symbol("b"), Symbol("."), []Expression{Symbol("c")}}}}, nil},
+		{[]string{"(", ".", "b", ")"}, nil, errors.New("syntax error")},
+		{[]string{"(", "a", ".", ")"}, nil, errors.New("syntax error")},
+		{[]string{"(", "lambda", "(", "a", ".", "b", "c", ")", "a", ")"}, nil, errors.New("syntax error")},
+		{[]string{"#(", "a", ".", "b", ")"}, nil, errors.New("syntax error")},
+		{[]string{"."}, nil, errors.New("syntax error")},
 	}
 	for _, c := range testCases {
 		ret, err := Parse(&c.input)
@@ -0,0 +1,607 @@
+package goscheme
+
+import (
+	"errors"
+	"fmt"
+)
+
+// identifier is the symbol inserted by a macro template. It is renamed by a unique key when bound by the expansion,
+// so it never captures the identifiers of the user. Free identifiers refer to the binding of the original name in the
+// environment where the macro is defined.
+type identifier struct {
+	// name is the original symbol, a string or *identifier inserted by an outer expansion
+	name Expression
+	env  *Env
+	key  Symbol
+}
+
+func newIdentifier(name Expression, env *Env) *identifier {
+	// the key is uninterned so that it never conflicts with the symbols of the user
+	return &identifier{name: name, env: env, key: gensym(symbolName(name))}
+}
+
+// String returns the original name of the identifier.
+func (id *identifier) String() string {
+	return symbolName(id)
+}
+
+// symbolName returns the name of the symbol with the renaming of macro expansion stripped.
+func symbolName(exp Expression) string {
+	switch s := exp.(type) {
+	case *identifier:
+		return symbolName(s.name)
+	case Symbol:
+		return string(s)
+	case string:
+		return s
+	default:
+		return fmt.Sprintf("%v", exp)
+	}
+}
+
+// isKeyword checks whether the expression is the symbol name, including the symbol inserted by macro expansion.
+func isKeyword(exp Expression, name string) bool {
+	return IsSymbol(exp) && symbolName(exp) == name
+}
+
+// ellipsisMatch holds the forms matched by a pattern followed by ellipsis, one element for each repetition.
+type ellipsisMatch []Expression
+
+// syntaxRule is a pattern and the template to expand when the pattern matches.
+type syntaxRule struct {
+	pattern  []Expression
+	template Expression
+}
+
+// Macro is the syntax transformer created by syntax-rules. Should only use with pointer.
+type Macro struct {
+	ellipsis string
+	literals []string
+	rules    []syntaxRule
+	// env is the environment of the macro definition to resolve the identifiers inserted by templates
+	env *Env
+}
+
+// String returns the string representing the *Macro.
+func (m *Macro) String() string {
+	return "#[Macro]"
+}
+
+// IsMacro checks whether the expression value is a *Macro.
+func IsMacro(exp Expression) bool {
+	_, ok := exp.(*Macro)
+	return ok
+}
+
+// Transform expands the macro use form in env with the first matching rule.
+func (m *Macro) Transform(form []Expression, env *Env) (Expression, error) {
+	for _, rule := range m.rules {
+		bindings := make(map[string]Expression)
+		if m.matchList(rule.pattern[1:], form[1:], env, bindings) {
+			return m.expand(rule.template, bindings, make(map[Expression]*identifier))
+		}
+	}
+	return UndefObj, fmt.Errorf("syntax error: no syntax rule matches %v", expToPrintString(form))
+}
+
+func (m *Macro) isEllipsis(exp Expression) bool {
+	return m.ellipsis != "" && isKeyword(exp, m.ellipsis)
+}
+
+func (m *Macro) isLiteral(name string) bool {
+	for _, l := range m.literals {
+		if l == name {
+			return true
+		}
+	}
+	return false
+}
+
+// matchLiteral checks whether the form in env is the literal like free-identifier=?, they match when the form refers
+// to the binding of the literal where the macro is defined, or both are unbound and have the same name.
+func (m *Macro) matchLiteral(name string, form Expression, env *Env) bool {
+	if !IsSymbol(form) {
+		return false
+	}
+	formEnv, formSymbol := env.binding(form)
+	literalEnv, literalSymbol := m.env.binding(Symbol(name))
+	if formEnv == nil && literalEnv == nil {
+		return symbolName(form) == name
+	}
+	return formEnv == literalEnv && formSymbol == literalSymbol
+}
+
+func isDot(exp Expression) bool {
+	return exp == Symbol(".")
+}
+
+func (m *Macro) match(pattern, form Expression, env *Env, bindings map[string]Expression) bool {
+	switch p := pattern.(type) {
+	case []Expression:
+		f, ok := form.([]Expression)
+		return ok && m.matchList(p, f, env, bindings)
+	case vectorLiteral:
+		f, ok := form.(vectorLiteral)
+		return ok && m.matchList(p, f, env, bindings)
+	}
+	if IsSymbol(pattern) {
+		name := symbolName(pattern)
+		switch {
+		case name == "_":
+		case m.isLiteral(name):
+			return m.matchLiteral(name, form, env)
+		default:
+			bindings[name] = form
+		}
+		return true
+	}
+	return isEqual(pattern, form)
+}
+
+// matchList matches the list pattern which may contain an ellipsis and a dotted tail against the list form.
+func (m *Macro) matchList(pattern, form []Expression, env *Env, bindings map[string]Expression) bool {
+	var tail Expression
+	if n := len(pattern); n >= 2 && isDot(pattern[n-2]) {
+		tail, pattern = pattern[n-1], pattern[:n-2]
+	}
+	ellipsisIndex := -1
+	for i := 1; i < len(pattern); i++ {
+		if m.isEllipsis(pattern[i]) {
+			ellipsisIndex = i - 1
+			break
+		}
+	}
+	before, after := pattern, []Expression(nil)
+	repeatCount := 0
+	if ellipsisIndex >= 0 {
+		before, after = pattern[:ellipsisIndex], pattern[ellipsisIndex+2:]
+		repeatCount = len(form) - len(before) - len(after)
+		if repeatCount < 0 {
+			return false
+		}
+	} else if len(form) < len(before) || (tail == nil && len(form) != len(before)) {
+		return false
+	}
+
+	for i, p := range before {
+		if !m.match(p, form[i], env, bindings) {
+			return false
+		}
+	}
+	rest := form[len(before):]
+	if ellipsisIndex >= 0 {
+		repeated := pattern[ellipsisIndex]
+		matches := make([]map[string]Expression, repeatCount)
+		for i := range matches {
+			matches[i] = make(map[string]Expression)
+			if !m.match(repeated, rest[i], env, matches[i]) {
+				return false
+			}
+		}
+		for _, name := range m.patternVariables(repeated) {
+			seq := make(ellipsisMatch, repeatCount)
+			for i := range matches {
+				seq[i] = matches[i][name]
+			}
+			bindings[name] = seq
+		}
+		rest = rest[repeatCount:]
+		for i, p := range after {
+			if !m.match(p, rest[i], env, bindings) {
+				return false
+			}
+		}
+		rest = rest[len(after):]
+	}
+	if tail != nil {
+		return m.match(tail, rest, env, bindings)
+	}
+	return true
+}
+
+// patternVariables returns the names of the pattern variables in the pattern.
+func (m *Macro) patternVariables(pattern Expression) (ret []string) {
+	switch p := pattern.(type) {
+	case []Expression:
+		for _, e := range p {
+			ret = append(ret, m.patternVariables(e)...)
+		}
+	case vectorLiteral:
+		for _, e := range p {
+			ret = append(ret, m.patternVariables(e)...)
+		}
+	default:
+		if IsSymbol(p) && !isDot(p) && !m.isEllipsis(p) {
+			if name := symbolName(p); name != "_" && !m.isLiteral(name) {
+				ret = append(ret, name)
+			}
+		}
+	}
+	return
+}
+
+// expand instantiates the template with the pattern variable bindings, the other symbols of the template are renamed
+// to identifiers, renames keeps the same symbol renamed to the same identifier in one expansion.
+func (m *Macro) expand(template Expression, bindings map[string]Expression, renames map[Expression]*identifier) (Expression, error) {
+	switch t := template.(type) {
+	case []Expression:
+		if len(t) == 2 && m.isEllipsis(t[0]) {
+			// (... template) escapes the ellipsis in the template
+			escaped := *m
+			escaped.ellipsis = ""
+			return escaped.expand(t[1], bindings, renames)
+		}
+		return m.expandList(t, bindings, renames)
+	case vectorLiteral:
+		ret, err := m.expandList(t, bindings, renames)
+		if err != nil {
+			return UndefObj, err
+		}
+		return vectorLiteral(ret), nil
+	}
+	if !IsSymbol(template) {
+		return template, nil
+	}
+	name := symbolName(template)
+	if v, ok := bindings[name]; ok {
+		if _, ok := v.(ellipsisMatch); ok {
+			return UndefObj, fmt.Errorf("syntax error: pattern variable %s used without ellipsis", name)
+		}
+		return v, nil
+	}
+	id, ok := renames[template]
+	if !ok {
+		id = newIdentifier(template, m.env)
+		renames[template] = id
+	}
+	return id, nil
+}
+
+func (m *Macro) expandList(template []Expression, bindings map[string]Expression, renames map[Expression]*identifier) ([]Expression, error) {
+	ret := make([]Expression, 0, len(template))
+	for i := 0; i < len(template); i++ {
+		if isDot(template[i]) && i+1 < len(template) {
+			tail, err := m.expand(template[i+1], bindings, renames)
+			if err != nil {
+				return nil, err
+			}
+			if l, ok := tail.([]Expression); ok {
+				return append(ret, l...), nil
+			}
+			return append(ret, Symbol("."), tail), nil
+		}
+		depth := 0
+		for i+depth+1 < len(template) && m.isEllipsis(template[i+depth+1]) {
+			depth++
+		}
+		if depth == 0 {
+			e, err := m.expand(template[i], bindings, renames)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, e)
+			continue
+		}
+		expanded, err := m.expandEllipsis(template[i], bindings, renames, depth)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, expanded...)
+		i += depth
+	}
+	return ret, nil
+}
+
+// expandEllipsis expands the template followed by depth ellipses once for each repetition of its pattern variables.
+func (m *Macro) expandEllipsis(template Expression, bindings map[string]Expression, renames map[Expression]*identifier, depth int) ([]Expression, error) {
+	count := -1
+	var names []string
+	for _, name := range m.patternVariables(template) {
+		seq, ok := bindings[name].(ellipsisMatch)
+		if !ok {
+			continue
+		}
+		if count >= 0 && len(seq) != count {
+			return nil, fmt.Errorf("syntax error: pattern variables of %v have different lengths", expToPrintString(template))
+		}
+		count = len(seq)
+		names = append(names, name)
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("syntax error: no pattern variable to repeat in %v", expToPrintString(template))
+	}
+	var ret []Expression
+	for i := 0; i < count; i++ {
+		repetition := make(map[string]Expression, len(bindings))
+		for k, v := range bindings {
+			repetition[k] = v
+		}
+		for _, name := range names {
+			repetition[name] = bindings[name].(ellipsisMatch)[i]
+		}
+		if depth > 1 {
+			expanded, err := m.expandEllipsis(template, repetition, renames, depth-1)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, expanded...)
+			continue
+		}
+		e, err := m.expand(template, repetition, renames)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, e)
+	}
+	return ret, nil
+}
+
+// evalSyntaxRules creates the *Macro, the form is (syntax-rules [ellipsis] (literal ...) (pattern template) ...).
+func evalSyntaxRules(args []Expression, env *Env) (Expression, error) {
+	m := &Macro{ellipsis: "...", env: env}
+	if len(args) > 0 && IsSymbol(args[0]) {
+		m.ellipsis = symbolName(args[0])
+		args = args[1:]
+	}
+	if len(args) < 1 {
+		return UndefObj, errors.New("syntax-rules: syntax error (literals missing)")
+	}
+	literals, ok := args[0].([]Expression)
+	if !ok {
+		return UndefObj, errors.New("syntax-rules: syntax error (literals must be a list)")
+	}
+	for _, l := range literals {
+		if !IsSymbol(l) {
+			return UndefObj, fmt.Errorf("syntax-rules: literal %v is not a symbol", expToPrintString(l))
+		}
+		m.literals = append(m.literals, symbolName(l))
+	}
+	for _, r := range args[1:] {
+		rule, ok := r.([]Expression)
+		if !ok || len(rule) != 2 {
+			return UndefObj, fmt.Errorf("syntax-rules: invalid rule %v", expToPrintString(r))
+		}
+		pattern, ok := rule[0].([]Expression)
+		if !ok || len(pattern) == 0 {
+			return UndefObj, fmt.Errorf("syntax-rules: invalid pattern %v", expToPrintString(rule[0]))
+		}
+		m.rules = append(m.rules, syntaxRule{pattern: pattern, template: rule[1]})
+	}
+	return m, nil
+}
+
+// evalTransformer evaluates the transformer spec such as (syntax-rules ...) and passes the *Macro to then.
+func (m *machine) evalTransformer(spec Expression, env *Env, then func(m *machine, macro *Macro) error) {
+	m.evalThen(spec, env, func(m *machine, v Expression) error {
+		macro, ok := v.(*Macro)
+		if !ok {
+			return fmt.Errorf("%v is not a syntax transformer", valueToString(v))
+		}
+		return then(m, macro)
+	})
+}
+
+func evalDefineSyntax(m *machine, args []Expression, env *Env) error {
+	if len(args) != 2 {
+		return errors.New("define-syntax: syntax error (requires keyword and transformer)")
+	}
+	sym, err := transExpressionToSymbol(args[0])
+	if err != nil {
+		return err
+	}
+	m.evalTransformer(args[1], env, func(m *machine, macro *Macro) error {
+		env.Set(sym, macro)
+		m.ret(UndefObj)
+		return nil
+	})
+	return nil
+}
+
+func evalLetSyntax(m *machine, args []Expression, env *Env) error {
+	return evalSyntaxBindings(m, "let-syntax", args, env, false)
+}
+
+func evalLetrecSyntax(m *machine, args []Expression, env *Env) error {
+	return evalSyntaxBindings(m, "letrec-syntax", args, env, true)
+}
+
+// evalSyntaxBindings binds the macros and evaluates the body, the transformers of letrec-syntax are evaluated in the
+// new environment so they can refer to each other.
+func evalSyntaxBindings(m *machine, name string, args []Expression, env *Env, recursive bool) error {
+	if len(args) < 2 {
+		return fmt.Errorf("%s: syntax error (%s should pass the bindings and body)", name, name)
+	}
+	bindings, ok := args[0].([]Expression)
+	if !ok {
+		return fmt.Errorf("%s: syntax error (not a valid binding)", name)
+	}
+	newEnv := &Env{outer: env, frame: make(map[Symbol]Expression)}
+	transformerEnv := env
+	if recursive {
+		transformerEnv = newEnv
+	}
+	symbols := make([]Symbol, len(bindings))
+	for i, exp := range bindings {
+		binding, ok := exp.([]Expression)
+		if !ok || len(binding) != 2 {
+			return fmt.Errorf("%s: syntax error (not a valid binding)", name)
+		}
+		sym, err := transExpressionToSymbol(binding[0])
+		if err != nil {
+			return err
+		}
+		symbols[i] = sym
+	}
+	var next func(m *machine, i int) error
+	next = func(m *machine, i int) error {
+		if i == len(bindings) {
+			m.evalBody(args[1:], newEnv)
+			return nil
+		}
+		m.evalTransformer(bindings[i].([]Expression)[1], transformerEnv, func(m *machine, macro *Macro) error {
+			newEnv.Set(symbols[i], macro)
+			return next(m, i+1)
+		})
+		return nil
+	}
+	return next(m, 0)
+}
+
+// transformer is the macro transforming the macro use form to the expanded syntax tree.
+type transformer interface {
+	Transform(form []Expression, env *Env) (Expression, error)
+}
+
+// LispMacro is the non-hygienic macro defined by define-macro, its body transforms the argument forms as data.
+// Should only use with pointer.
+type LispMacro struct {
+	params []Symbol
+	// rest is the parameter bound to the rest argument forms, empty if the macro accepts fixed arguments
+	rest Symbol
+	body []Expression
+	env  *Env
+}
+
+// String returns the string representing the *LispMacro.
+func (m *LispMacro) String() string {
+	return "#[LispMacro]"
+}
+
+// IsLispMacro checks whether the expression value is a *LispMacro.
+func IsLispMacro(exp Expression) bool {
+	_, ok := exp.(*LispMacro)
+	return ok
+}
+
+// Transform evaluates the body with the argument forms as data and converts the result back to the syntax tree.
+func (m *LispMacro) Transform(form []Expression, _ *Env) (Expression, error) {
+	args := form[1:]
+	if len(args) < len(m.params) || (m.rest == "" && len(args) != len(m.params)) {
+		return UndefObj, fmt.Errorf("macro %v requires %d arguments but %d arguments provided", symbolName(form[0]), len(m.params), len(args))
+	}
+	newEnv := &Env{outer: m.env, frame: make(map[Symbol]Expression)}
+	var data []Expression
+	for _, arg := range args {
+		datum, err := evalQuote([]Expression{arg}, newEnv)
+		if err != nil {
+			return UndefObj, err
+		}
+		data = append(data, datum)
+	}
+	for i, param := range m.params {
+		newEnv.Set(param, data[i])
+	}
+	if m.rest != "" {
+		rest, _ := listImpl(data[len(m.params):]...)
+		newEnv.Set(m.rest, rest)
+	}
+	ret, err := EvalAll(m.body, newEnv)
+	if err != nil {
+		return UndefObj, err
+	}
+	return datumToExpression(ret), nil
+}
+
+// datumToExpression converts the data such as the result of define-macro transformers back to the syntax tree.
+func datumToExpression(datum Expression) Expression {
+	switch d := datum.(type) {
+	case NilType:
+		return []Expression{}
+	case *Pair:
+		ret := make([]Expression, 0)
+		var current Expression = d
+		for !IsNullExp(current) {
+			p, ok := current.(*Pair)
+			if !ok {
+				return append(ret, Symbol("."), datumToExpression(current))
+			}
+			ret = append(ret, datumToExpression(p.Car))
+			current = p.Cdr
+		}
+		return ret
+	default:
+		return datum
+	}
+}
+
+// evalDefineMacro defines the non-hygienic macro, the forms are (define-macro (name . params) body ...) and
+// (define-macro name (lambda params body ...)).
+func evalDefineMacro(args []Expression, env *Env) (Expression, error) {
+	if len(args) < 2 {
+		return UndefObj, errors.New("define-macro: syntax error (requires name and body)")
+	}
+	if spec, ok := args[0].([]Expression); ok && len(spec) > 0 {
+		return defineLispMacro(spec[0], spec[1:], args[1:], env)
+	}
+	lambda, ok := args[1].([]Expression)
+	if len(args) != 2 || !ok || len(lambda) < 3 || !isKeyword(lambda[0], "lambda") {
+		return UndefObj, errors.New("define-macro: syntax error (requires a lambda expression)")
+	}
+	return defineLispMacro(args[0], lambda[1], lambda[2:], env)
+}
+
+// evalDefmacro defines the non-hygienic macro with the form (defmacro name params body ...).
+func evalDefmacro(args []Expression, env *Env) (Expression, error) {
+	if len(args) < 3 {
+		return UndefObj, errors.New("defmacro: syntax error (requires name, parameters and body)")
+	}
+	return defineLispMacro(args[0], args[1], args[2:], env)
+}
+
+func defineLispMacro(name Expression, paramSpec Expression, body []Expression, env *Env) (Expression, error) {
+	sym, err := transExpressionToSymbol(name)
+	if err != nil {
+		return UndefObj, err
+	}
+	params, rest, err := parseFormals(paramSpec)
+	if err != nil {
+		return UndefObj, err
+	}
+	env.Set(sym, &LispMacro{params: params, rest: rest, body: body, env: env})
+	return UndefObj, nil
+}
+
+// expandOnce expands the form once if it is a macro use, returns false when the form is not a macro use.
+func expandOnce(form Expression, env *Env) (Expression, bool, error) {
+	ops, ok := form.([]Expression)
+	if !ok || len(ops) == 0 || !IsSymbol(ops[0]) {
+		return form, false, nil
+	}
+	head, err := env.findSymbol(ops[0])
+	if err != nil {
+		return form, false, nil
+	}
+	t, ok := head.(transformer)
+	if !ok {
+		return form, false, nil
+	}
+	ret, err := t.Transform(ops, env)
+	return ret, true, err
+}
+
+// macroexpander returns the control function expanding the datum as a macro use in the environment of the call, the
+// expansion repeats until the form is not a macro use if repeat is set. The expanded form is returned as data.
+func macroexpander(repeat bool) controlFunc {
+	return func(m *machine, args ...Expression) error {
+		if isCircularList(args[0], make(map[*Pair]int)) {
+			return fmt.Errorf("%v is circular", valueToString(args[0]))
+		}
+		form := datumToExpression(args[0])
+		for {
+			expanded, ok, err := expandOnce(form, m.env)
+			if err != nil {
+				return err
+			}
+			form = expanded
+			if !ok || !repeat {
+				break
+			}
+		}
+		ret, err := evalQuote([]Expression{form}, m.env)
+		if err != nil {
+			return err
+		}
+		m.ret(ret)
+		return nil
+	}
+}
@@ -0,0 +1,102 @@
+package goscheme
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSyntaxRules(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"(define-syntax swap! (syntax-rules () ((_ a b) (let ((tmp a)) (set! a b) (set! b tmp)))))" +
+			"(define tmp 1) (define y 2) (swap! tmp y) (list tmp y)", "(2 1)"},
+		{"(define-syntax my-or (syntax-rules () ((_) #f) ((_ e) e) ((_ e r ...) (let ((t e)) (if t t (my-or r ...))))))" +
+			"(define t 5) (my-or #f t)", "5"},
+		{"(my-or)", "#f"},
+		{"(let ((if list)) (my-or #f 2))", "2"},
+		{"(define (helper) 'global) (define-syntax call-helper (syntax-rules () ((_) (helper))))" +
+			"(let ((helper (lambda () 'local))) (call-helper))", "global"},
+		{"(define-syntax my-if (syntax-rules (then else) ((_ c then t else e) (if c t e)))) (my-if #f then 1 else 2)", "2"},
+		{"(define-syntax flatten (syntax-rules () ((_ (a ...) ...) '(a ... ...)))) (flatten (1 2) () (3))", "(1 2 3)"},
+		{"(define-syntax group (syntax-rules () ((_ (k v ...) ...) (list (list 'k v ...) ...)))) (group (a 1 2) (b))",
+			"((a 1 2) (b))"},
+		{"(define-syntax last (syntax-rules () ((_ a ... z) 'z))) (last 1 2 3)", "3"},
+		{"(define-syntax split (syntax-rules () ((_ a . rest) '(a rest)))) (split 1 2 3)", "(1 (2 3))"},
+		{"(define-syntax vfirst (syntax-rules () ((_ #(a b ...)) '(a #(b ...))))) (vfirst #(x y z))", "(x #(y z))"},
+		{"(define-syntax my-list (syntax-rules ::: () ((_ x :::) (list x :::)))) (my-list 1 2 3)", "(1 2 3)"},
+		{"(define-syntax def-lister (syntax-rules () ((_ name) (define-syntax name (syntax-rules () ((_ x (... ...)) (list x (... ...))))))))" +
+			"(def-lister lst) (lst 1 2 3)", "(1 2 3)"},
+		{"(define-syntax my-let (syntax-rules () ((_ ((n v) ...) body ...) ((lambda (n ...) body ...) v ...))))" +
+			"(my-let ((a 1) (b 2)) (+ a b))", "3"},
+		{"(define (make) (define x 10) (define-syntax get-x (syntax-rules () ((_) x))) (let ((x 20)) (get-x))) (make)", "10"},
+		{"(let-syntax ((double (syntax-rules () ((_ x) (* x 2))))) (double 21))", "42"},
+		{"(let ((x 'outer)) (let-syntax ((m (syntax-rules () ((_) x)))) (let ((x 'inner)) (m))))", "outer"},
+		{"(letrec-syntax ((ev? (syntax-rules () ((_) #t) ((_ x . r) (od? . r)))) (od? (syntax-rules () ((_) #f) ((_ x . r) (ev? . r)))))" +
+			"(ev? 1 2 3 4))", "#t"},
+		{"(define-syntax ten (syntax-rules () ((_) 10))) (let ((ten (lambda () 1))) (ten))", "1"},
+		{"(define-syntax while (syntax-rules () ((_ c body ...) (let lp () (when c body ... (lp))))))" +
+			"(define i 0) (define sum 0) (while (< i 5) (set! sum (+ sum i)) (set! i (+ i 1))) (list i sum)", "(5 10)"},
+		// the literals match the identifiers referring to the same binding like free-identifier=?
+		{"(define-syntax kw (syntax-rules (=>) ((_ a => b) 'arrow) ((_ a b c) 'plain))) (kw 1 => 2)", "arrow"},
+		{"(let ((=> 1)) (kw 1 => 2))", "plain"},
+		{"(let ((=> 1)) (let-syntax ((kw2 (syntax-rules (=>) ((_ =>) 'arrow) ((_ x) 'other)))) (list (kw2 =>) (let ((=> 2)) (kw2 =>)))))",
+			"(arrow other)"},
+		// the body of let-syntax is evaluated in the tail position with the internal definitions
+		{"(define (count-down n) (let-syntax ((dec (syntax-rules () ((_ x) (- x 1))))) (if (= n 0) 'done (count-down (dec n)))))" +
+			"(count-down 100000)", "done"},
+		{"(letrec-syntax ((one (syntax-rules () ((_) 1)))) (define two (+ (one) (one))) (define (three) (+ two 1)) (three))", "3"},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+
+	for _, input := range []string{"(my-if 1 2 3)", "(let-syntax ((double (syntax-rules () ((_ x) (* x 2))))) 1) (double 1)",
+		"(define-syntax bad (syntax-rules () ((_ a ...) a))) (bad 1)", "(define-syntax bad 1)", "(syntax-rules (1))"} {
+		_, err := EvalAll(strToToken(input), env)
+		assert.NotNil(t, err, input)
+	}
+}
+
+func TestLispMacro(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"(define-macro (my-unless c . body) (list 'if c #f (cons 'begin body))) (my-unless #f 1 2)", "2"},
+		{"(my-unless #t 1)", "#f"},
+		{"(define-macro (swap! a b) (list 'let (list (list 'tmp a)) (list 'set! a b) (list 'set! b 'tmp)))" +
+			"(define x 1) (define y 2) (swap! x y) (list x y)", "(2 1)"},
+		{"(defmacro inc! (v) (list 'set! v (list '+ v 1))) (define n 41) (inc! n) n", "42"},
+		{"(define-macro my-quote (lambda (x) (list 'quote x))) (my-quote (a \"b\" #\\c 1))", "(a \"b\" #\\c 1)"},
+		{"(define-macro (all . forms) (cons 'list forms)) (all 1 (+ 1 1) 3)", "(1 2 3)"},
+		{"(define-macro (capture) 'x) (let ((x 'captured)) (capture))", "captured"},
+		{"(macroexpand-1 '(my-unless c a b))", "(if c #f (begin a b))"},
+		{"(define-macro (my-when c . body) (list 'my-unless (list 'not c) (cons 'begin body))) (macroexpand-1 '(my-when c a))",
+			"(my-unless (not c) (begin a))"},
+		{"(macroexpand '(my-when c a))", "(if (not c) #f (begin (begin a)))"},
+		{"(macroexpand '(+ 1 2))", "(+ 1 2)"},
+		{"(macroexpand 'x)", "x"},
+		{"(define-syntax my-inc (syntax-rules () ((_ x) (+ x 1)))) (macroexpand '(my-inc 2))", "(+ 2 1)"},
+		// macroexpand is a procedure expanding in the environment of the call
+		{"(map macroexpand '((my-inc 1) (my-when c a)))", "((+ 1 1) (if (not c) #f (begin (begin a))))"},
+		{"(let-syntax ((two (syntax-rules () ((_) 2)))) (macroexpand '(two)))", "2"},
+		{"(with-exception-handler (lambda (e) '(my-inc 3)) (lambda () (macroexpand (raise-continuable 'x))))", "(+ 3 1)"},
+		{"(with-exception-handler (lambda (e) (syntax-rules () ((_) 5))) (lambda () (let-syntax ((five (raise-continuable 'x))) (five))))", "5"},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+
+	for _, input := range []string{"(swap! x)", "(define-macro)", "(define-macro m 1)", "(defmacro m (1) 1)", "(macroexpand)"} {
+		_, err := EvalAll(strToToken(input), env)
+		assert.NotNil(t, err, input)
+	}
+}
@@ -0,0 +1,753 @@
+package goscheme
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/cmplx"
+	"strconv"
+	"strings"
+)
+
+// Number is implemented by all the numeric types in scheme.
+//
+// The numeric tower is ordered as Integer < *BigInt < *Rational < Real < Complex. Operations on two numbers promote the lower one to the
+// type of the higher one, and exact results are always normalized back to the smallest exact type able to hold them.
+type Number interface {
+	// IsExact reports whether the number is exact.
+	IsExact() bool
+}
+
+// Integer is an exact integer that fits in a machine word.
+type Integer int64
+
+// IsExact implements the Number interface.
+func (i Integer) IsExact() bool {
+	return true
+}
+
+// String returns the string representing the Integer.
+func (i Integer) String() string {
+	return strconv.FormatInt(int64(i), 10)
+}
+
+// BigInt is an exact integer which overflows Integer. Should only use with pointer.
+type BigInt big.Int
+
+// IsExact implements the Number interface.
+func (b *BigInt) IsExact() bool {
+	return true
+}
+
+// String returns the string representing the *BigInt.
+func (b *BigInt) String() string {
+	return (*big.Int)(b).String()
+}
+
+// Rational is an exact rational number which is not an integer. Should only use with pointer.
+type Rational big.Rat
+
+// IsExact implements the Number interface.
+func (r *Rational) IsExact() bool {
+	return true
+}
+
+// String returns the string representing the *Rational in the form of numerator/denominator.
+func (r *Rational) String() string {
+	return (*big.Rat)(r).RatString()
+}
+
+// Real is an inexact real number.
+type Real float64
+
+// IsExact implements the Number interface.
+func (r Real) IsExact() bool {
+	return false
+}
+
+// String returns the string representing the Real, always with a decimal point or exponent to mark it inexact.
+func (r Real) String() string {
+	f := float64(r)
+	switch {
+	case math.IsInf(f, 1):
+		return "+inf.0"
+	case math.IsInf(f, -1):
+		return "-inf.0"
+	case math.IsNaN(f):
+		return "+nan.0"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	s = strings.Replace(s, "e+", "e", 1)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// Complex is an inexact complex number with non-zero imaginary part, there are no exact complex numbers.
+type Complex complex128
+
+// IsExact implements the Number interface.
+func (c Complex) IsExact() bool {
+	return false
+}
+
+// String returns the string representing the Complex in rectangular form such as 1.0+2.0i.
+func (c Complex) String() string {
+	imag := Real(imag(c)).String()
+	if imag[0] != '+' && imag[0] != '-' {
+		imag = "+" + imag
+	}
+	return Real(real(c)).String() + imag + "i"
+}
+
+const (
+	levelInteger = iota
+	levelBigInt
+	levelRational
+	levelReal
+	levelComplex
+)
+
+func numberLevel(n Number) int {
+	switch n.(type) {
+	case Integer:
+		return levelInteger
+	case *BigInt:
+		return levelBigInt
+	case *Rational:
+		return levelRational
+	case Complex:
+		return levelComplex
+	default:
+		return levelReal
+	}
+}
+
+// normalizeBigInt returns the smallest exact type holding the value of b.
+func normalizeBigInt(b *big.Int) Number {
+	if b.IsInt64() {
+		return Integer(b.Int64())
+	}
+	return (*BigInt)(b)
+}
+
+// normalizeRat returns the smallest exact type holding the value of r.
+func normalizeRat(r *big.Rat) Number {
+	if r.IsInt() {
+		return normalizeBigInt(new(big.Int).Set(r.Num()))
+	}
+	return (*Rational)(r)
+}
+
+// normalizeComplex drops the zero imaginary part of c.
+func normalizeComplex(c complex128) Number {
+	if imag(c) == 0 {
+		return Real(real(c))
+	}
+	return Complex(c)
+}
+
+func toComplex(n Number) complex128 {
+	if c, ok := n.(Complex); ok {
+		return complex128(c)
+	}
+	return complex(toFloat(n), 0)
+}
+
+func toRat(n Number) *big.Rat {
+	switch v := n.(type) {
+	case Integer:
+		return big.NewRat(int64(v), 1)
+	case *BigInt:
+		return new(big.Rat).SetInt((*big.Int)(v))
+	case *Rational:
+		return (*big.Rat)(v)
+	}
+	return nil
+}
+
+func toBigInt(n Number) *big.Int {
+	switch v := n.(type) {
+	case Integer:
+		return big.NewInt(int64(v))
+	case *BigInt:
+		return (*big.Int)(v)
+	}
+	return nil
+}
+
+func toFloat(n Number) float64 {
+	switch v := n.(type) {
+	case Integer:
+		return float64(v)
+	case *BigInt:
+		f, _ := new(big.Float).SetInt((*big.Int)(v)).Float64()
+		return f
+	case *Rational:
+		f, _ := (*big.Rat)(v).Float64()
+		return f
+	case Real:
+		return float64(v)
+	}
+	return math.NaN()
+}
+
+func numAdd(a, b Number) Number {
+	switch maxLevel(a, b) {
+	case levelInteger:
+		x, y := a.(Integer), b.(Integer)
+		if c := x + y; (c > x) == (y > 0) {
+			return c
+		}
+		fallthrough
+	case levelBigInt:
+		return normalizeBigInt(new(big.Int).Add(toBigInt(a), toBigInt(b)))
+	case levelRational:
+		return normalizeRat(new(big.Rat).Add(toRat(a), toRat(b)))
+	case levelComplex:
+		return normalizeComplex(toComplex(a) + toComplex(b))
+	default:
+		return Real(toFloat(a) + toFloat(b))
+	}
+}
+
+func numSub(a, b Number) Number {
+	switch maxLevel(a, b) {
+	case levelInteger:
+		x, y := a.(Integer), b.(Integer)
+		if c := x - y; (c < x) == (y > 0) {
+			return c
+		}
+		fallthrough
+	case levelBigInt:
+		return normalizeBigInt(new(big.Int).Sub(toBigInt(a), toBigInt(b)))
+	case levelRational:
+		return normalizeRat(new(big.Rat).Sub(toRat(a), toRat(b)))
+	case levelComplex:
+		return normalizeComplex(toComplex(a) - toComplex(b))
+	default:
+		return Real(toFloat(a) - toFloat(b))
+	}
+}
+
+func numMul(a, b Number) Number {
+	switch maxLevel(a, b) {
+	case levelInteger:
+		x, y := a.(Integer), b.(Integer)
+		if x == 0 || y == 0 {
+			return Integer(0)
+		}
+		c := x * y
+		if c/y == x && !(x == -1 && y == math.MinInt64) && !(y == -1 && x == math.MinInt64) {
+			return c
+		}
+		fallthrough
+	case levelBigInt:
+		return normalizeBigInt(new(big.Int).Mul(toBigInt(a), toBigInt(b)))
+	case levelRational:
+		return normalizeRat(new(big.Rat).Mul(toRat(a), toRat(b)))
+	case levelComplex:
+		return normalizeComplex(toComplex(a) * toComplex(b))
+	default:
+		return Real(toFloat(a) * toFloat(b))
+	}
+}
+
+func numDiv(a, b Number) (Number, error) {
+	switch maxLevel(a, b) {
+	case levelComplex:
+		return normalizeComplex(toComplex(a) / toComplex(b)), nil
+	case levelReal:
+		return Real(toFloat(a) / toFloat(b)), nil
+	}
+	y := toRat(b)
+	if y.Sign() == 0 {
+		return Integer(0), errors.New("/: division by zero")
+	}
+	return normalizeRat(new(big.Rat).Quo(toRat(a), y)), nil
+}
+
+// numEqual checks whether the two numbers are numerically equal.
+func numEqual(a, b Number) bool {
+	if maxLevel(a, b) == levelComplex {
+		return toComplex(a) == toComplex(b)
+	}
+	c, ok := numCompare(a, b)
+	return ok && c == 0
+}
+
+// numCompare returns -1, 0 or 1 when a is less than, equal to or greater than b.
+// The comparison of NaN with any number returns an ok false. Both a and b must be real numbers.
+func numCompare(a, b Number) (result int, ok bool) {
+	switch maxLevel(a, b) {
+	case levelInteger:
+		x, y := a.(Integer), b.(Integer)
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case levelBigInt:
+		return toBigInt(a).Cmp(toBigInt(b)), true
+	case levelRational:
+		return toRat(a).Cmp(toRat(b)), true
+	default:
+		if a.IsExact() != b.IsExact() {
+			// compare exactly when possible to avoid losing the precision of big integers and rationals
+			x, err1 := exactNumber(a)
+			y, err2 := exactNumber(b)
+			if err1 == nil && err2 == nil {
+				return numCompare(x, y)
+			}
+		}
+		x, y := toFloat(a), toFloat(b)
+		switch {
+		case math.IsNaN(x) || math.IsNaN(y):
+			return 0, false
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+}
+
+func numNegate(n Number) Number {
+	return numSub(Integer(0), n)
+}
+
+// numAbs returns the absolute value of the real number n.
+func numAbs(n Number) Number {
+	if c, ok := numCompare(n, Integer(0)); ok && c < 0 {
+		return numNegate(n)
+	}
+	return n
+}
+
+func maxLevel(a, b Number) int {
+	l1, l2 := numberLevel(a), numberLevel(b)
+	if l1 > l2 {
+		return l1
+	}
+	return l2
+}
+
+// exactNumber converts the number to the exact number with the same value.
+func exactNumber(n Number) (Number, error) {
+	if n.IsExact() {
+		return n, nil
+	}
+	f := toFloat(n)
+	if _, ok := n.(Complex); ok || math.IsInf(f, 0) || math.IsNaN(f) {
+		return Integer(0), fmt.Errorf("exact: %v has no exact representation", n)
+	}
+	return normalizeRat(new(big.Rat).SetFloat64(f)), nil
+}
+
+// simplestRational returns the simplest rational number in the closed interval [lo, hi].
+func simplestRational(lo, hi *big.Rat) *big.Rat {
+	switch {
+	case lo.Sign() > 0:
+		return simplestPositiveRational(lo, hi)
+	case hi.Sign() < 0:
+		r := simplestPositiveRational(new(big.Rat).Neg(hi), new(big.Rat).Neg(lo))
+		return r.Neg(r)
+	default:
+		return new(big.Rat)
+	}
+}
+
+func simplestPositiveRational(lo, hi *big.Rat) *big.Rat {
+	fl := new(big.Int).Quo(lo.Num(), lo.Denom())
+	floor := new(big.Rat).SetInt(fl)
+	if floor.Cmp(lo) == 0 {
+		return floor
+	}
+	next := new(big.Rat).SetInt(fl.Add(fl, big.NewInt(1)))
+	if next.Cmp(hi) <= 0 {
+		return next
+	}
+	// lo and hi have the same integer part, continue with the reciprocal of their fraction parts
+	rest := simplestPositiveRational(
+		new(big.Rat).Inv(new(big.Rat).Sub(hi, floor)),
+		new(big.Rat).Inv(new(big.Rat).Sub(lo, floor)))
+	return rest.Add(floor, rest.Inv(rest))
+}
+
+// inexactNumber converts the number to the inexact number nearest to it.
+func inexactNumber(n Number) Number {
+	if c, ok := n.(Complex); ok {
+		return c
+	}
+	return Real(toFloat(n))
+}
+
+// isRealNumber checks whether the number has no imaginary part.
+func isRealNumber(n Number) bool {
+	_, ok := n.(Complex)
+	return !ok
+}
+
+// sqrtNumber returns the principal square root of n, which is exact when n is an exact perfect square.
+func sqrtNumber(n Number) Number {
+	switch v := n.(type) {
+	case Complex:
+		return normalizeComplex(cmplx.Sqrt(complex128(v)))
+	case Real:
+		if v < 0 {
+			return Complex(complex(0, math.Sqrt(-float64(v))))
+		}
+		return Real(math.Sqrt(float64(v)))
+	}
+	r := toRat(n)
+	if r.Sign() < 0 {
+		return Complex(complex(0, toFloat(sqrtNumber(normalizeRat(new(big.Rat).Neg(r))))))
+	}
+	num, denom := new(big.Int).Sqrt(r.Num()), new(big.Int).Sqrt(r.Denom())
+	if new(big.Int).Mul(num, num).Cmp(r.Num()) == 0 && new(big.Int).Mul(denom, denom).Cmp(r.Denom()) == 0 {
+		return normalizeRat(new(big.Rat).SetFrac(num, denom))
+	}
+	return Real(math.Sqrt(toFloat(n)))
+}
+
+// parseNumber reads the scheme number literal such as 12, -3.5, 1/3, 1e10, 1+2i, 1@1.57, #xFF and #e1.0.
+// Literals without decimal point or exponent are exact unless the #i prefix is specified.
+func parseNumber(s string, radix int) (Number, bool) {
+	exactness := byte(0)
+	for len(s) >= 2 && s[0] == '#' {
+		switch s[1] {
+		case 'e', 'E', 'i', 'I':
+			if exactness != 0 {
+				return nil, false
+			}
+			exactness = s[1] | 0x20
+		case 'x', 'X':
+			radix = 16
+		case 'b', 'B':
+			radix = 2
+		case 'o', 'O':
+			radix = 8
+		case 'd', 'D':
+			radix = 10
+		default:
+			return nil, false
+		}
+		s = s[2:]
+	}
+	n, ok := parseComplex(s, radix)
+	if !ok {
+		return nil, false
+	}
+	switch exactness {
+	case 'e':
+		e, err := exactNumber(n)
+		if err != nil {
+			return nil, false
+		}
+		return e, true
+	case 'i':
+		return inexactNumber(n), true
+	}
+	return n, true
+}
+
+// parseComplex reads the complex number in rectangular form such as 1+2i, +i and -2.5i, or in polar form such as
+// 3@1.57. Literal without imaginary part is read as real number, the others are inexact even when the parts are written
+// as exact numbers, so 1+0i is read as 1.0.
+func parseComplex(s string, radix int) (Number, bool) {
+	if i := strings.IndexByte(s, '@'); i > 0 {
+		magnitude, ok1 := parseReal(s[:i], radix)
+		angle, ok2 := parseReal(s[i+1:], radix)
+		if !ok1 || !ok2 {
+			return nil, false
+		}
+		return makePolar(magnitude, angle), true
+	}
+	if !strings.HasSuffix(s, "i") {
+		return parseReal(s, radix)
+	}
+	body := s[:len(s)-1]
+	// the imaginary part starts at the last sign which is not the sign of an exponent
+	split := -1
+	for i := len(body) - 1; i >= 0; i-- {
+		if (body[i] == '+' || body[i] == '-') && (i == 0 || !isExponentMarker(body[i-1], radix)) {
+			split = i
+			break
+		}
+	}
+	if split < 0 {
+		return nil, false
+	}
+	var realPart Number = Integer(0)
+	if split > 0 {
+		var ok bool
+		if realPart, ok = parseReal(body[:split], radix); !ok {
+			return nil, false
+		}
+	}
+	imagText := body[split:]
+	if imagText == "+" || imagText == "-" {
+		imagText += "1"
+	}
+	imagPart, ok := parseReal(imagText, radix)
+	if !ok {
+		return nil, false
+	}
+	return makeRectangular(realPart, imagPart), true
+}
+
+func isExponentMarker(c byte, radix int) bool {
+	return radix == 10 && (c == 'e' || c == 'E')
+}
+
+// makeRectangular returns the complex number composed with the real and imaginary parts. Complex numbers are always
+// inexact, so the result is inexact even if both parts are exact, e.g. 1.0 for 1 and 0.
+func makeRectangular(realPart, imagPart Number) Number {
+	return normalizeComplex(complex(toFloat(realPart), toFloat(imagPart)))
+}
+
+// makePolar returns the complex number with the magnitude and angle, which is inexact like makeRectangular.
+func makePolar(magnitude, angle Number) Number {
+	return normalizeComplex(cmplx.Rect(toFloat(magnitude), toFloat(angle)))
+}
+
+func parseReal(s string, radix int) (Number, bool) {
+	switch s {
+	case "+inf.0":
+		return Real(math.Inf(1)), true
+	case "-inf.0":
+		return Real(math.Inf(-1)), true
+	case "+nan.0", "-nan.0":
+		return Real(math.NaN()), true
+	}
+	if isIntegerLiteral(s, radix) {
+		b, ok := new(big.Int).SetString(strings.TrimPrefix(s, "+"), radix)
+		if !ok {
+			return nil, false
+		}
+		return normalizeBigInt(b), true
+	}
+	if i := strings.IndexByte(s, '/'); i > 0 {
+		return parseRational(s[:i], s[i+1:], radix)
+	}
+	if radix != 10 || !isDecimalLiteral(s) {
+		return nil, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return nil, false
+	}
+	return Real(f), true
+}
+
+func parseRational(numerator, denominator string, radix int) (Number, bool) {
+	if !isIntegerLiteral(numerator, radix) || !isIntegerLiteral(denominator, radix) ||
+		denominator[0] == '+' || denominator[0] == '-' {
+		return nil, false
+	}
+	num, _ := new(big.Int).SetString(strings.TrimPrefix(numerator, "+"), radix)
+	denom, _ := new(big.Int).SetString(denominator, radix)
+	if denom.Sign() == 0 {
+		return nil, false
+	}
+	return normalizeRat(new(big.Rat).SetFrac(num, denom)), true
+}
+
+func isIntegerLiteral(s string, radix int) bool {
+	if len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+		s = s[1:]
+	}
+	if len(s) == 0 {
+		return false
+	}
+	for _, r := range s {
+		if !isDigit(r, radix) {
+			return false
+		}
+	}
+	return true
+}
+
+// isDecimalLiteral checks the format of decimal like 1.5, .5, 1., 1e10 and -2.5E-3.
+func isDecimalLiteral(s string) bool {
+	if len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+		s = s[1:]
+	}
+	mantissa, exponent := s, ""
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa, exponent = s[:i], s[i+1:]
+		if !isIntegerLiteral(exponent, 10) {
+			return false
+		}
+	}
+	digits := 0
+	dots := 0
+	for _, r := range mantissa {
+		switch {
+		case r == '.':
+			dots++
+		case isDigit(r, 10):
+			digits++
+		default:
+			return false
+		}
+	}
+	return digits > 0 && dots <= 1
+}
+
+func isDigit(r rune, radix int) bool {
+	var v int
+	switch {
+	case r >= '0' && r <= '9':
+		v = int(r - '0')
+	case r >= 'a' && r <= 'z':
+		v = int(r-'a') + 10
+	case r >= 'A' && r <= 'Z':
+		v = int(r-'A') + 10
+	default:
+		return false
+	}
+	return v < radix
+}
+
+// isIntegerNumber checks whether the number is an exact or inexact integer.
+func isIntegerNumber(n Number) bool {
+	switch v := n.(type) {
+	case Integer, *BigInt:
+		return true
+	case Real:
+		f := float64(v)
+		return !math.IsInf(f, 0) && f == math.Trunc(f)
+	default:
+		return false
+	}
+}
+
+// integerDivide divides the integer n by d and returns the quotient and remainder.
+// The quotient is rounded toward negative infinity when floor is true, otherwise toward zero.
+// The results are inexact if any of the arguments is inexact.
+func integerDivide(n, d Number, floor bool) (q, r Number, err error) {
+	x, _ := exactNumber(n)
+	y, _ := exactNumber(d)
+	divisor := toBigInt(y)
+	if divisor.Sign() == 0 {
+		return nil, nil, errors.New("integer division by zero")
+	}
+	quo, rem := new(big.Int).QuoRem(toBigInt(x), divisor, new(big.Int))
+	if floor && rem.Sign() != 0 && rem.Sign() != divisor.Sign() {
+		quo.Sub(quo, big.NewInt(1))
+		rem.Add(rem, divisor)
+	}
+	q, r = normalizeBigInt(quo), normalizeBigInt(rem)
+	if !n.IsExact() || !d.IsExact() {
+		q, r = inexactNumber(q), inexactNumber(r)
+	}
+	return q, r, nil
+}
+
+// gcdNumber returns the non-negative greatest common divisor of the integers a and b.
+func gcdNumber(a, b Number) Number {
+	x, _ := exactNumber(a)
+	y, _ := exactNumber(b)
+	ret := normalizeBigInt(new(big.Int).GCD(nil, nil, new(big.Int).Abs(toBigInt(x)), new(big.Int).Abs(toBigInt(y))))
+	if !a.IsExact() || !b.IsExact() {
+		return inexactNumber(ret)
+	}
+	return ret
+}
+
+// exptNumber returns base raised to the power exponent.
+// The result is exact when base is exact and exponent is an exact integer.
+func exptNumber(base, exponent Number) (Number, error) {
+	switch e := exponent.(type) {
+	case Integer, *BigInt:
+		if base.IsExact() {
+			return exactExpt(base, toBigInt(e))
+		}
+		if c, ok := base.(Complex); ok {
+			return normalizeComplex(cmplx.Pow(complex128(c), toComplex(e))), nil
+		}
+		return Real(math.Pow(toFloat(base), toFloat(e))), nil
+	}
+	_, complexBase := base.(Complex)
+	_, complexExponent := exponent.(Complex)
+	if complexBase || complexExponent || toFloat(base) < 0 {
+		if toComplex(base) == 0 {
+			return Real(0), nil
+		}
+		return normalizeComplex(cmplx.Pow(toComplex(base), toComplex(exponent))), nil
+	}
+	return Real(math.Pow(toFloat(base), toFloat(exponent))), nil
+}
+
+func exactExpt(base Number, exponent *big.Int) (Number, error) {
+	r := toRat(base)
+	e := new(big.Int).Abs(exponent)
+	num := new(big.Int).Exp(r.Num(), e, nil)
+	denom := new(big.Int).Exp(r.Denom(), e, nil)
+	if exponent.Sign() < 0 {
+		if num.Sign() == 0 {
+			return Integer(0), errors.New("expt: division by zero")
+		}
+		num, denom = denom, num
+	}
+	return normalizeRat(new(big.Rat).SetFrac(num, denom)), nil
+}
+
+// roundNumber rounds the real number with the mode, which is one of floor, ceiling, truncate and round.
+// Exact numbers are rounded to exact integers and inexact numbers are rounded to inexact integers.
+func roundNumber(n Number, mode string) Number {
+	if r, ok := n.(Real); ok {
+		f := float64(r)
+		switch mode {
+		case "floor":
+			return Real(math.Floor(f))
+		case "ceiling":
+			return Real(math.Ceil(f))
+		case "truncate":
+			return Real(math.Trunc(f))
+		default:
+			return Real(math.RoundToEven(f))
+		}
+	}
+	rat := toRat(n)
+	if rat.IsInt() {
+		return n
+	}
+	q, m := new(big.Int).DivMod(rat.Num(), rat.Denom(), new(big.Int))
+	// now q is the floor of the number and m/denom is the fraction part in (0, 1)
+	switch mode {
+	case "ceiling":
+		q.Add(q, big.NewInt(1))
+	case "truncate":
+		if rat.Sign() < 0 {
+			q.Add(q, big.NewInt(1))
+		}
+	case "round":
+		if c := new(big.Int).Mul(m, big.NewInt(2)).Cmp(rat.Denom()); c > 0 || (c == 0 && q.Bit(0) == 1) {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return normalizeBigInt(q)
+}
+
+// numberToString returns the representation of the number in radix.
+// Only exact numbers can be represented in radix other than 10.
+func numberToString(n Number, radix int) (string, error) {
+	if radix == 10 {
+		return valueToString(n), nil
+	}
+	switch v := n.(type) {
+	case Integer, *BigInt:
+		return toBigInt(v).Text(radix), nil
+	case *Rational:
+		r := (*big.Rat)(v)
+		return r.Num().Text(radix) + "/" + r.Denom().Text(radix), nil
+	}
+	return "", fmt.Errorf("number->string: inexact number %v can only be represented in radix 10", n)
+}
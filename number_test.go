@@ -0,0 +1,279 @@
+package goscheme
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseNumber(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected Expression
+		ok       bool
+	}{
+		{"3", Integer(3), true},
+		{"-3", Integer(-3), true},
+		{"+3", Integer(3), true},
+		{"3.0", Real(3), true},
+		{".5", Real(0.5), true},
+		{"1.", Real(1), true},
+		{"1e3", Real(1000), true},
+		{"-2.5E-1", Real(-0.25), true},
+		{"#xff", Integer(255), true},
+		{"#b101", Integer(5), true},
+		{"#o17", Integer(15), true},
+		{"#e1.0", Integer(1), true},
+		{"#i3", Real(3), true},
+		{"#x#i10", Real(16), true},
+		{"4/2", Integer(2), true},
+		{"1/0", nil, false},
+		{"1/-2", nil, false},
+		{"1/", nil, false},
+		{"/2", nil, false},
+		{"+i", Complex(1i), true},
+		{"-i", Complex(-1i), true},
+		{"1+2i", Complex(1 + 2i), true},
+		{"1.5-2.5i", Complex(1.5 - 2.5i), true},
+		{"-2i", Complex(-2i), true},
+		{"1e2+1e-2i", Complex(100 + 0.01i), true},
+		{"1+0i", Real(1), true},
+		{"3@0", Real(3), true},
+		{"#e1+0i", Integer(1), true},
+		{"i", nil, false},
+		{"2i", nil, false},
+		{"a+i", nil, false},
+		{"+", nil, false},
+		{"-", nil, false},
+		{"...", nil, false},
+		{"1+", nil, false},
+		{"inf", nil, false},
+		{"0x10", nil, false},
+		{"#e#i1", nil, false},
+	}
+	for _, c := range testCases {
+		n, ok := parseNumber(c.input, 10)
+		assert.Equal(t, c.ok, ok, c.input)
+		if c.ok {
+			assert.Equal(t, c.expected, n, c.input)
+		}
+	}
+	n, ok := parseNumber("123456789012345678901234567890", 10)
+	assert.True(t, ok)
+	assert.Equal(t, "123456789012345678901234567890", valueToString(n))
+	n, ok = parseNumber("-6/4", 10)
+	assert.True(t, ok)
+	assert.Equal(t, "-3/2", valueToString(n))
+	n, ok = parseNumber("#e1.5", 10)
+	assert.True(t, ok)
+	assert.Equal(t, "3/2", valueToString(n))
+}
+
+func TestNumberString(t *testing.T) {
+	testCases := []struct {
+		input    Number
+		expected string
+	}{
+		{Integer(3), "3"},
+		{Real(3), "3.0"},
+		{Real(-0.5), "-0.5"},
+		{Real(1e21), "1e21"},
+		{Real(1e-7), "1e-07"},
+		{Complex(1 + 2i), "1.0+2.0i"},
+		{Complex(-1.5 - 1i), "-1.5-1.0i"},
+	}
+	for _, c := range testCases {
+		assert.Equal(t, c.expected, valueToString(c.input))
+	}
+}
+
+func TestNumericTower(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"(+ 1 2)", "3"},
+		{"(+ 1 2.0)", "3.0"},
+		{"(- 5)", "-5"},
+		{"(* 2 2.5)", "5.0"},
+		{"(/ 6 3)", "2"},
+		{"(/ 1 2.0)", "0.5"},
+		{"(+ 9223372036854775807 1)", "9223372036854775808"},
+		{"(- -9223372036854775808 1)", "-9223372036854775809"},
+		{"(* 4294967296 4294967296)", "18446744073709551616"},
+		{"(- 9223372036854775808 1)", "9223372036854775807"},
+		{"(exact? 9223372036854775808)", "#t"},
+		{"(exact? 1)", "#t"},
+		{"(exact? 1.0)", "#f"},
+		{"(inexact? 1.0)", "#t"},
+		{"(exact 2.0)", "2"},
+		{"(inexact 2)", "2.0"},
+		{"(exact->inexact 1)", "1.0"},
+		{"(inexact->exact 1e20)", "100000000000000000000"},
+		{"(= 1 1.0)", "#t"},
+		{"(< 1 1.5)", "#t"},
+		{"(> 9223372036854775808 9223372036854775807)", "#t"},
+		{"(integer? 2.0)", "#t"},
+		{"(integer? 2.5)", "#f"},
+		{"(number? 'a)", "#f"},
+		{"(/ 1 3)", "1/3"},
+		{"(/ 6 4)", "3/2"},
+		{"(+ 1/3 2/3)", "1"},
+		{"(+ 1/3 1)", "4/3"},
+		{"(* 1/3 0.5)", "0.16666666666666666"},
+		{"(- 1/2)", "-1/2"},
+		{"(/ 1/2 1/4)", "2"},
+		{"(< 1/3 0.34)", "#t"},
+		{"(= 1/2 0.5)", "#t"},
+		{"(exact 1.5)", "3/2"},
+		{"(exact? 1/3)", "#t"},
+		{"(inexact 1/4)", "0.25"},
+		{"(numerator 6/4)", "3"},
+		{"(denominator 6/4)", "2"},
+		{"(denominator 5)", "1"},
+		{"(numerator 0.5)", "1.0"},
+		{"(denominator 0.5)", "2.0"},
+		{"(rationalize 3/10 1/10)", "1/3"},
+		{"(rationalize .3 1/10)", "0.3333333333333333"},
+		{"(rationalize -3/10 1/10)", "-1/3"},
+		{"(rationalize 1/4 1/4)", "0"},
+		{"(rational? 1/2)", "#t"},
+		{"(rational? +inf.0)", "#f"},
+		{"(+ 1/10 1/10 1/10)", "3/10"},
+		{"(+ 1+2i 1)", "2.0+2.0i"},
+		{"(* +i +i)", "-1.0"},
+		{"(- 1+2i 1+2i)", "0.0"},
+		{"(/ 1+i 2)", "0.5+0.5i"},
+		{"(= 1+2i (make-rectangular 1 2))", "#t"},
+		{"(= 1+2i 1)", "#f"},
+		{"(make-rectangular 1 0)", "1.0"},
+		{"(make-rectangular 1 2)", "1.0+2.0i"},
+		{"(list (exact? 1+2i) (exact? (make-rectangular 1 0)) (exact? (make-polar 2 0)))", "(#f #f #f)"},
+		{"(make-polar 2 0)", "2.0"},
+		{"(real-part 1+2i)", "1.0"},
+		{"(imag-part 1+2i)", "2.0"},
+		{"(imag-part 3)", "0"},
+		{"(magnitude 3+4i)", "5.0"},
+		{"(magnitude -5)", "5"},
+		{"(angle -1)", "3.141592653589793"},
+		{"(angle +i)", "1.5707963267948966"},
+		{"(make-polar 2 0.0)", "2.0"},
+		{"(angle (make-polar 2 1.5))", "1.5"},
+		{"(real? 1+2i)", "#f"},
+		{"(complex? 1+2i)", "#t"},
+		{"(real? 1.5)", "#t"},
+		{"(sqrt -4)", "0.0+2.0i"},
+		{"(sqrt 16)", "4"},
+		{"(sqrt 1/4)", "1/2"},
+		{"(sqrt 2)", "1.4142135623730951"},
+		{"(sqrt -1.0)", "0.0+1.0i"},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+
+	_, err := EvalAll(strToToken("(/ 1 0)"), env)
+	assert.NotNil(t, err)
+	_, err = EvalAll(strToToken("(exact +inf.0)"), env)
+	assert.NotNil(t, err)
+	_, err = EvalAll(strToToken("(< 1+i 2)"), env)
+	assert.NotNil(t, err)
+
+	ret, _ := EvalAll(strToToken(`
+		(define (fib n)
+			(define (iter a b n) (if (= n 0) a (iter b (+ a b) (- n 1))))
+			(iter 0 1 n))
+		(fib 100)`), env)
+	assert.Equal(t, "354224848179261915075", valueToString(ret))
+}
+
+func TestNumericLibrary(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"(quotient 17 5)", "3"},
+		{"(quotient -17 5)", "-3"},
+		{"(remainder 17 5)", "2"},
+		{"(remainder -17 5)", "-2"},
+		{"(modulo -17 5)", "3"},
+		{"(modulo 17 -5)", "-3"},
+		{"(remainder 17.0 5)", "2.0"},
+		{"(remainder 100000000000000000000 7)", "2"},
+		{"(call-with-values (lambda () (floor/ -7 2)) list)", "(-4 1)"},
+		{"(call-with-values (lambda () (truncate/ -7 2)) list)", "(-3 -1)"},
+		{"(floor-quotient -7 2)", "-4"},
+		{"(truncate-remainder -7 2)", "-1"},
+		{"(gcd 32 -36)", "4"},
+		{"(gcd)", "0"},
+		{"(lcm 32 -36)", "288"},
+		{"(lcm 32.0 -36)", "288.0"},
+		{"(lcm)", "1"},
+		{"(abs -7)", "7"},
+		{"(abs -1/2)", "1/2"},
+		{"(min 3 1 2)", "1"},
+		{"(max 3 4.0)", "4.0"},
+		{"(max 1 2.0 3)", "3.0"},
+		{"(expt 2 10)", "1024"},
+		{"(expt 2 100)", "1267650600228229401496703205376"},
+		{"(expt 2 -2)", "1/4"},
+		{"(expt 2/3 2)", "4/9"},
+		{"(expt 2.0 3)", "8.0"},
+		{"(expt 4 1/2)", "2.0"},
+		{"(expt 0 0)", "1"},
+		{"(exp 0)", "1.0"},
+		{"(log 1)", "0.0"},
+		{"(log 8 2)", "3.0"},
+		{"(log -1)", "0.0+3.141592653589793i"},
+		{"(sin 0)", "0.0"},
+		{"(cos 0)", "1.0"},
+		{"(atan 1 1)", "0.7853981633974483"},
+		{"(asin 2)", "1.5707963267948966+1.3169578969248164i"},
+		{"(call-with-values (lambda () (exact-integer-sqrt 17)) list)", "(4 1)"},
+		{"(floor 2.5)", "2.0"},
+		{"(ceiling 2.5)", "3.0"},
+		{"(round 2.5)", "2.0"},
+		{"(round 3.5)", "4.0"},
+		{"(round 7/2)", "4"},
+		{"(round 5/2)", "2"},
+		{"(round -7/2)", "-4"},
+		{"(floor -7/2)", "-4"},
+		{"(ceiling -7/2)", "-3"},
+		{"(truncate -7/2)", "-3"},
+		{"(truncate -2.7)", "-2.0"},
+		{"(number->string 255 16)", `"ff"`},
+		{"(number->string 1/3 2)", `"1/11"`},
+		{"(number->string 2.5)", `"2.5"`},
+		{`(string->number "ff" 16)`, "255"},
+		{`(string->number "1e3")`, "1000.0"},
+		{`(string->number "abc")`, "#f"},
+		{"(< 1 2 3)", "#t"},
+		{"(< 1 3 2)", "#f"},
+		{"(> 3 2 1)", "#t"},
+		{"(= 1 1 1.0)", "#t"},
+		{"(<= 1 1 2)", "#t"},
+		{"(>= 2 2 3)", "#f"},
+		{"(zero? 0.0)", "#t"},
+		{"(positive? -1)", "#f"},
+		{"(negative? -1/2)", "#t"},
+		{"(odd? 7)", "#t"},
+		{"(even? -4)", "#t"},
+		{"(exact-integer? 4.0)", "#f"},
+		{"(nan? +nan.0)", "#t"},
+		{"(square 1/2)", "1/4"},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+
+	for _, input := range []string{"(quotient 1 0)", "(modulo 1.5 1)", "(exact-integer-sqrt -1)", "(number->string 1.5 2)",
+		"(expt 0 -1)", "(max 1+i 2)"} {
+		_, err := EvalAll(strToToken(input), env)
+		assert.NotNil(t, err, input)
+	}
+}
@@ -1,8 +1,25 @@
 package goscheme
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
 
-// Parse read and parse the tokens to construct a syntax tree represents in nested slices.
+// standardInput is the tokenizer of the standard input read by read.
+var standardInput = NewTokenizerFromReader(os.Stdin)
+
+// readerMacros maps the prefix tokens to the syntax keywords they abbreviate.
+var readerMacros = map[string]Symbol{
+	"'":  intern("quote"),
+	"`":  intern("quasiquote"),
+	",":  intern("unquote"),
+	",@": intern("unquote-splicing"),
+}
+
+// Parse read and parse the tokens to construct a syntax tree represents in nested slices, the atoms are read to the
+// typed datums by readAtom.
 func Parse(tokens *[]string) (ret []Expression, err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -25,25 +42,163 @@ func readTokens(tokens *[]string) Expression {
 
 	switch token {
 	case "(":
-		ret := make([]Expression, 0)
-		for len(*tokens) >= 0 && (*tokens)[0] != ")" {
-			nextPart := readTokens(tokens)
-			ret = append(ret, nextPart)
-		}
-		if len(*tokens) == 0 {
-			panic("syntax error: missing ')'")
-		}
-		*tokens = (*tokens)[1:]
-		return ret
+		return readList(tokens, true)
+	case "#(":
+		return vectorLiteral(readList(tokens, false))
+	case "#u8(":
+		return readBytevector(tokens)
 	case ")":
 		panic("syntax error: unexpected ')'")
-	case "'":
+	case ".":
+		panic("syntax error: unexpected '.'")
+	case "'", "`", ",", ",@":
 		ret := make([]Expression, 0, 4)
-		ret = append(ret, "quote")
+		ret = append(ret, readerMacros[token])
 		nextPart := readTokens(tokens)
 		ret = append(ret, nextPart)
 		return ret
 	default:
-		return token
+		atom, err := readAtom(token)
+		if err != nil {
+			panic(err.Error())
+		}
+		return atom
+	}
+}
+
+// readAtom converts the token to the typed datum: String, bool, Number, Char or Symbol, the symbol token may be
+// written between vertical lines.
+func readAtom(token string) (Expression, error) {
+	switch {
+	case len(token) >= 2 && strings.HasPrefix(token, "\"") && strings.HasSuffix(token, "\""):
+		return String(token[1 : len(token)-1]), nil
+	case len(token) >= 2 && strings.HasPrefix(token, "|") && strings.HasSuffix(token, "|"):
+		return intern(token[1 : len(token)-1]), nil
+	case token == "#t" || token == "#true":
+		return true, nil
+	case token == "#f" || token == "#false":
+		return false, nil
+	case strings.HasPrefix(token, `#\`):
+		c, ok := parseChar(token)
+		if !ok {
+			return nil, fmt.Errorf("syntax error: unknown character %s", token)
+		}
+		return c, nil
+	}
+	if n, ok := parseNumber(token, 10); ok {
+		return n, nil
 	}
+	return intern(token), nil
+}
+
+// readExpression reads the tokens left in the expression built by hand such as []Expression{"+", "1", "2"} like Parse,
+// the expression without tokens is returned as it is.
+func readExpression(exp Expression) (Expression, error) {
+	if !hasTokens(exp) {
+		return exp, nil
+	}
+	switch e := exp.(type) {
+	case string:
+		return readAtom(e)
+	case []Expression:
+		return readExpressions(e)
+	case vectorLiteral:
+		ret, err := readExpressions(e)
+		return vectorLiteral(ret), err
+	}
+	return exp, nil
+}
+
+func readExpressions(exps []Expression) ([]Expression, error) {
+	ret := make([]Expression, len(exps))
+	for i, e := range exps {
+		datum, err := readExpression(e)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = datum
+	}
+	return ret, nil
+}
+
+func hasTokens(exp Expression) bool {
+	switch e := exp.(type) {
+	case string:
+		return true
+	case []Expression:
+		for _, sub := range e {
+			if hasTokens(sub) {
+				return true
+			}
+		}
+	case vectorLiteral:
+		for _, sub := range e {
+			if hasTokens(sub) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readList reads the expressions until the matching ')'. The dotted tail of (a b . c) is kept as the "." symbol
+// followed by the last expression like the other syntax trees, it is only allowed when dotted is true.
+func readList(tokens *[]string, dotted bool) []Expression {
+	ret := make([]Expression, 0)
+	for len(*tokens) > 0 && (*tokens)[0] != ")" {
+		if (*tokens)[0] == "." {
+			*tokens = (*tokens)[1:]
+			if !dotted || len(ret) == 0 || len(*tokens) == 0 || (*tokens)[0] == ")" {
+				panic("syntax error: bad dotted list")
+			}
+			ret = append(ret, Symbol("."), readTokens(tokens))
+			if len(*tokens) > 0 && (*tokens)[0] != ")" {
+				panic("syntax error: more than one datum after '.'")
+			}
+			break
+		}
+		nextPart := readTokens(tokens)
+		ret = append(ret, nextPart)
+	}
+	if len(*tokens) == 0 {
+		panic("syntax error: missing ')'")
+	}
+	*tokens = (*tokens)[1:]
+	return ret
+}
+
+// readBytevector reads the bytes until the matching ')' and constructs the bytevectorLiteral.
+func readBytevector(tokens *[]string) bytevectorLiteral {
+	elements := readList(tokens, false)
+	bytes := make([]byte, len(elements))
+	for i, e := range elements {
+		b, err := expressionToByte(e)
+		if err != nil {
+			panic(fmt.Sprintf("syntax error: %v is not a byte", expToPrintString(e)))
+		}
+		bytes[i] = b
+	}
+	return bytevectorLiteral(bytes)
+}
+
+// readDatum reads the tokens of the next datum from the tokenizer and converts it to the data like quote, EOFObj is
+// returned at the end of the input.
+func readDatum(t *Tokenizer) (Expression, error) {
+	tokens, ok := t.nextDatumTokens()
+	if !ok {
+		if len(tokens) == 0 {
+			return EOFObj, nil
+		}
+		return UndefObj, errors.New("read: unexpected end of input")
+	}
+	exps, err := Parse(&tokens)
+	if err != nil {
+		return UndefObj, fmt.Errorf("read: %s", err)
+	}
+	return evalQuote(exps, nil)
+}
+
+// readFunc reads the next datum from the standard input.
+func readFunc(args ...Expression) (Expression, error) {
+	return readDatum(standardInput)
 }
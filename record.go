@@ -0,0 +1,192 @@
+package goscheme
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RecordType is the type of records defined by define-record-type. Should only use with pointer.
+type RecordType struct {
+	name   string
+	fields []Symbol
+}
+
+// String returns the string representing the *RecordType.
+func (t *RecordType) String() string {
+	return fmt.Sprintf("#[RecordType %s]", t.name)
+}
+
+// fieldIndex returns the index of the field in the record values.
+func (t *RecordType) fieldIndex(field Symbol) (int, bool) {
+	for i, f := range t.fields {
+		if f == field {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Record is the instance of a RecordType. Should only use with pointer.
+type Record struct {
+	recordType *RecordType
+	values     []Expression
+}
+
+// String returns the string representing the *Record such as #<record point x=1 y=2>.
+func (r *Record) String() string {
+	return writeDatum(r)
+}
+
+// IsRecord checks whether the expression value is a *Record or *RecordType.
+func IsRecord(exp Expression) bool {
+	switch exp.(type) {
+	case *Record, *RecordType:
+		return true
+	default:
+		return false
+	}
+}
+
+// evalDefineRecordType defines the record type, constructor, predicate, accessors and modifiers in env.
+// (define-record-type <point> (make-point x y) point? (x point-x set-point-x!) (y point-y))
+func evalDefineRecordType(args []Expression, env *Env) (Expression, error) {
+	if len(args) < 3 {
+		return UndefObj, errors.New("define-record-type: syntax error (requires type name, constructor and predicate)")
+	}
+	typeName, err := transExpressionToSymbol(args[0])
+	if err != nil {
+		return UndefObj, fmt.Errorf("define-record-type: %s", err)
+	}
+	recordType := &RecordType{name: strings.TrimSuffix(strings.TrimPrefix(string(typeName), "<"), ">")}
+	var procedures []Function
+	for _, spec := range args[3:] {
+		fieldSpec, ok := spec.([]Expression)
+		if !ok || len(fieldSpec) < 1 || len(fieldSpec) > 3 {
+			return UndefObj, fmt.Errorf("define-record-type: invalid field spec %v", expToPrintString(spec))
+		}
+		var names []Symbol
+		for _, e := range fieldSpec {
+			name, err := transExpressionToSymbol(e)
+			if err != nil {
+				return UndefObj, fmt.Errorf("define-record-type: %s", err)
+			}
+			names = append(names, name)
+		}
+		if _, ok := recordType.fieldIndex(names[0]); ok {
+			return UndefObj, fmt.Errorf("define-record-type: duplicate field %v", names[0])
+		}
+		index := len(recordType.fields)
+		recordType.fields = append(recordType.fields, names[0])
+		if len(names) > 1 {
+			procedures = append(procedures, NewFunction(string(names[1]), recordAccessor(recordType, index, names[1]), 1, 1))
+		}
+		if len(names) > 2 {
+			procedures = append(procedures, NewFunction(string(names[2]), recordModifier(recordType, index, names[2]), 2, 2))
+		}
+	}
+
+	constructor, err := recordConstructor(recordType, args[1])
+	if err != nil {
+		return UndefObj, err
+	}
+	if constructor != nil {
+		procedures = append(procedures, *constructor)
+	}
+	predicateName, err := transExpressionToSymbol(args[2])
+	if err != nil {
+		return UndefObj, fmt.Errorf("define-record-type: %s", err)
+	}
+	procedures = append(procedures, NewFunction(string(predicateName), recordPredicate(recordType), 1, 1))
+
+	env.Set(typeName, recordType)
+	for _, p := range procedures {
+		env.Set(Symbol(p.name), p)
+	}
+	return UndefObj, nil
+}
+
+// recordConstructor returns the constructor Function from the spec, a symbol constructor initializes all fields in
+// order and #f means no constructor.
+func recordConstructor(recordType *RecordType, spec Expression) (*Function, error) {
+	if spec == false {
+		return nil, nil
+	}
+	var parts []Expression
+	switch s := spec.(type) {
+	case []Expression:
+		parts = s
+	default:
+		parts = []Expression{s}
+		for _, field := range recordType.fields {
+			parts = append(parts, field)
+		}
+	}
+	if len(parts) == 0 {
+		return nil, errors.New("define-record-type: constructor name missing")
+	}
+	name, err := transExpressionToSymbol(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("define-record-type: %s", err)
+	}
+	var indexes []int
+	for _, e := range parts[1:] {
+		field, err := transExpressionToSymbol(e)
+		if err != nil {
+			return nil, fmt.Errorf("define-record-type: %s", err)
+		}
+		i, ok := recordType.fieldIndex(field)
+		if !ok {
+			return nil, fmt.Errorf("define-record-type: %v is not a field of %s", field, recordType.name)
+		}
+		indexes = append(indexes, i)
+	}
+	fn := NewFunction(string(name), func(args ...Expression) (Expression, error) {
+		r := &Record{recordType: recordType, values: make([]Expression, len(recordType.fields))}
+		for i := range r.values {
+			r.values[i] = UndefObj
+		}
+		for i, index := range indexes {
+			r.values[index] = args[i]
+		}
+		return r, nil
+	}, len(indexes), len(indexes))
+	return &fn, nil
+}
+
+func recordPredicate(recordType *RecordType) commonFunction {
+	return func(args ...Expression) (Expression, error) {
+		r, ok := args[0].(*Record)
+		return ok && r.recordType == recordType, nil
+	}
+}
+
+// recordArg checks the argument is an instance of the recordType.
+func recordArg(recordType *RecordType, name Symbol, exp Expression) (*Record, error) {
+	r, ok := exp.(*Record)
+	if !ok || r.recordType != recordType {
+		return nil, fmt.Errorf("%v: %v is not a record of type %s", name, valueToString(exp), recordType.name)
+	}
+	return r, nil
+}
+
+func recordAccessor(recordType *RecordType, index int, name Symbol) commonFunction {
+	return func(args ...Expression) (Expression, error) {
+		r, err := recordArg(recordType, name, args[0])
+		if err != nil {
+			return UndefObj, err
+		}
+		return r.values[index], nil
+	}
+}
+
+func recordModifier(recordType *RecordType, index int, name Symbol) commonFunction {
+	return func(args ...Expression) (Expression, error) {
+		r, err := recordArg(recordType, name, args[0])
+		if err != nil {
+			return UndefObj, err
+		}
+		r.values[index] = args[1]
+		return UndefObj, nil
+	}
+}
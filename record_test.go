@@ -0,0 +1,47 @@
+package goscheme
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRecord_String(t *testing.T) {
+	pointType := &RecordType{name: "point", fields: []Symbol{"x", "y"}}
+	r := &Record{recordType: pointType, values: []Expression{Integer(1), String("a")}}
+	assert.Equal(t, `#<record point x=1 y="a">`, r.String())
+	assert.Equal(t, "#[RecordType point]", pointType.String())
+}
+
+func TestDefineRecordType(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"(define-record-type <point> (make-point x y) point? (x point-x set-point-x!) (y point-y)) (make-point 1 2)",
+			"#<record point x=1 y=2>"},
+		{"<point>", "#[RecordType point]"},
+		{"(point? (make-point 1 2))", "#t"},
+		{"(point? '(1 2))", "#f"},
+		{"(point-y (make-point 1 2))", "2"},
+		{"(define p (make-point 1 2)) (set-point-x! p 10) (point-x p)", "10"},
+		{"(equal? (make-point 1 '(2)) (make-point 1 '(2)))", "#t"},
+		{"(equal? (make-point 1 2) (make-point 1 3))", "#f"},
+		{"(define-record-type node (make-node value) node? (value node-value) (next node-next set-node-next!)) (make-node 1)",
+			"#<record node value=1 next=<UNDEF>>"},
+		{"(point? (make-node 1))", "#f"},
+		{"(define-record-type pair3 kons pair3? (a a-of) (b b-of)) (b-of (kons 1 2))", "2"},
+		{"(define h (make-hash-table)) (hash-table-set! h (make-point 1 2) 'p) (hash-table-ref h (make-point 1 2))", "p"},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+
+	for _, input := range []string{"(point-x (make-node 1))", "(make-point 1)", "(define-record-type bad (make-bad z) bad? (x))",
+		"(define-record-type bad (make-bad x) bad? (x) (x))", "(define-record-type bad (make-bad))"} {
+		_, err := EvalAll(strToToken(input), env)
+		assert.NotNil(t, err, input)
+	}
+}
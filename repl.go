@@ -19,19 +19,60 @@ var exit = make(chan os.Signal, 1)
 // if result > 0 missing ) , if result < 0 missing (, if result == 0 syntax check passed.
 func neededIndents(reader io.RuneReader) int {
 	stack := make([]rune, 0, 3)
+	// the last two runes read, used to skip the character literals #\( and #\)
+	var prev1, prev2 rune
+	// the depth of the nested block comments #| |#, the parentheses inside are skipped
+	comments := 0
+	// the parentheses in the string literals, the |...| symbols and the line comments are skipped too,
+	// delimiter is the rune closing the current string or symbol
+	var delimiter rune
+	escaped, inLineComment := false, false
 
 	for ch, _, err := reader.ReadRune(); err == nil; {
-		if ch == '(' {
+		isCharLiteral := prev2 == '#' && prev1 == '\\'
+		switch {
+		case delimiter != 0:
+			if escaped {
+				escaped = false
+			} else if ch == '\\' {
+				escaped = true
+			} else if ch == delimiter {
+				delimiter = 0
+			}
+			ch = 0
+		case inLineComment:
+			inLineComment = ch != '\n'
+			ch = 0
+		case isCharLiteral:
+		case prev1 == '#' && ch == '|':
+			comments++
+			ch = 0
+		case comments > 0 && prev1 == '|' && ch == '#':
+			comments--
+			ch = 0
+		case comments > 0:
+		case ch == '"' || ch == '|':
+			delimiter = ch
+			ch = 0
+		case ch == ';' && prev1 != '#':
+			// #; is the datum comment
+			inLineComment = true
+			ch = 0
+		case ch == '(':
 			stack = append(stack, ch)
-		} else if ch == ')' {
+		case ch == ')':
 			if len(stack)-1 < 0 {
 				return len(stack) - 1
 			}
 			stack = stack[:len(stack)-1]
 		}
+		prev2, prev1 = prev1, ch
 		ch, _, err = reader.ReadRune()
 	}
-	return len(stack)
+	if delimiter != 0 {
+		return len(stack) + comments + 1
+	}
+	return len(stack) + comments
 }
 
 // InterpreterMode represents mode the interpreter will run
@@ -54,6 +95,8 @@ type Interpreter struct {
 	mode              InterpreterMode
 	consoleWriter     prompt.ConsoleWriter
 	env               *Env
+	// foldCase keeps the state of the #!fold-case directive between the fragments
+	foldCase bool
 }
 
 // Run start the interpreter and evaluate the input.
@@ -88,8 +131,7 @@ func (i *Interpreter) runNormal() error {
 		i.currentFragment = append(i.currentFragment, '\n')
 		i.currentFragment = append(i.currentFragment, i.currentLineScript...)
 		if i.indents() == 0 {
-			tokenizer := NewTokenizerFromReader(bytes.NewReader(i.currentFragment))
-			tokens := tokenizer.Tokens()
+			tokens := i.fragmentTokens()
 			expTokens, err := Parse(&tokens)
 			if err != nil {
 				fmt.Printf("%s\n", err)
@@ -104,6 +146,16 @@ func (i *Interpreter) runNormal() error {
 	}
 }
 
+// fragmentTokens returns the tokens of the current fragment, the #!fold-case directive read in the previous fragments
+// still applies.
+func (i *Interpreter) fragmentTokens() []string {
+	tokenizer := NewTokenizerFromReader(bytes.NewReader(i.currentFragment))
+	tokenizer.foldCase = i.foldCase
+	tokens := tokenizer.Tokens()
+	i.foldCase = tokenizer.foldCase
+	return tokens
+}
+
 // check whether the input has syntax error
 func (i *Interpreter) check() {
 	var buf bytes.Buffer
@@ -221,8 +273,7 @@ func (i *Interpreter) evalPromptInput(input string) {
 	i.currentFragment = append(i.currentFragment, '\n')
 	i.currentFragment = append(i.currentFragment, i.currentLineScript...)
 	if i.indents() <= 0 {
-		tokenizer := NewTokenizerFromReader(bytes.NewReader(i.currentFragment))
-		tokens := tokenizer.Tokens()
+		tokens := i.fragmentTokens()
 		expTokens, err := Parse(&tokens)
 		if err != nil {
 			i.print(fmt.Sprintf("%s\n", err), prompt.Red)
@@ -232,14 +283,26 @@ func (i *Interpreter) evalPromptInput(input string) {
 		if err != nil {
 			i.print(fmt.Sprintf("err:=>%s\n", err), prompt.Red)
 		}
-		if shouldPrint(ret) && err == nil {
-			i.print(fmt.Sprintf("#=>%s\n", valueToString(ret)), prompt.Green)
+		if err == nil {
+			for _, line := range resultLines(ret) {
+				i.print(line, prompt.Green)
+			}
 		}
 		i.currentFragment = make([]byte, 0, 10)
 	}
 	i.printIndents()
 }
 
+// resultLines returns the lines to print the result in REPL, each of the multiple values on its own line.
+func resultLines(ret Expression) (lines []string) {
+	for _, v := range valuesToSlice(ret) {
+		if shouldPrint(v) {
+			lines = append(lines, fmt.Sprintf("#=>%s\n", valueToString(v)))
+		}
+	}
+	return
+}
+
 // NewFileInterpreter construct a *Interpreter from file.
 func NewFileInterpreter(reader io.Reader) *Interpreter {
 	return &Interpreter{input: reader, exit: exit, mode: NoneInteractive, env: setupBuiltinEnv()}
@@ -3,6 +3,7 @@ package goscheme
 import (
 	"bytes"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -16,9 +17,40 @@ func TestNeededIndents(t *testing.T) {
 		{input: "(fn x)", expected: 0},
 		{input: `(fn
 					x)`, expected: 0},
+		{input: `(display #\()`, expected: 0},
+		{input: `(display #\)`, expected: 1},
+		{input: "#| ( |# (fn)", expected: 0},
+		{input: "#| #| |# (", expected: 1},
+		{input: `(display #\|)`, expected: 0},
+		{input: `(display "#|")`, expected: 0},
+		{input: `(display "(\"(" #\")`, expected: 0},
+		{input: "(display 1) ; ( #|\n(fn)", expected: 0},
+		{input: `(display "abc`, expected: 2},
+		{input: "#;(fn) (", expected: 1},
+		{input: `(display '|a ( \| b|)`, expected: 0},
+		{input: `(display '|a (`, expected: 2},
 	}
 	for _, c := range testCases {
 		ret := neededIndents(bytes.NewReader([]byte(c.input)))
 		assert.Equal(t, c.expected, ret)
 	}
 }
+
+func TestFileInterpreter(t *testing.T) {
+	script := `#!/usr/bin/env goscheme
+#|
+  (define a 0)
+  #| nested ( |#
+|#
+(define a 1)
+#;(define a 2)
+#!fold-case
+(DEFINE B 'Sym)
+#!no-fold-case
+(define C (list a #;(ignored) b))`
+	env := setupBuiltinEnv()
+	assert.Nil(t, NewFileInterpreterWithEnv(strings.NewReader(script), env).Run())
+	ret, err := EvalAll(strToToken("C"), env)
+	assert.Nil(t, err)
+	assert.Equal(t, "(1 sym)", valueToString(ret))
+}
@@ -0,0 +1,464 @@
+package goscheme
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MutableString represents the string that can be modified by string-set! and string-fill!.
+// String literals are immutable in scheme, the procedures allocating new strings such as make-string, string-copy and
+// string-append return MutableString. MutableString should only use with pointer.
+type MutableString struct {
+	runes []rune
+}
+
+// NewMutableString construct a *MutableString with the content of s.
+func NewMutableString(s string) *MutableString {
+	return &MutableString{runes: []rune(s)}
+}
+
+// String return the string to write wrapping the content with quotes, the quotes, backslashes and control characters
+// are escaped like String.
+func (s *MutableString) String() string {
+	return quoteString(string(s.runes))
+}
+
+// stringEscapes maps the control characters written with the escape sequences to the escaped letters.
+var stringEscapes = map[rune]rune{
+	'\a': 'a',
+	'\b': 'b',
+	'\t': 't',
+	'\n': 'n',
+	'\r': 'r',
+}
+
+// quoteString returns the string literal of s which is read back to s.
+func quoteString(s string) string {
+	return quoteText(s, '"')
+}
+
+// quoteText wraps s with the delimiter, the delimiter, backslashes and control characters are escaped.
+func quoteText(s string, delimiter rune) string {
+	var buf strings.Builder
+	buf.WriteRune(delimiter)
+	for _, r := range s {
+		if r == delimiter || r == '\\' {
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		} else if escaped, ok := stringEscapes[r]; ok {
+			buf.WriteByte('\\')
+			buf.WriteRune(escaped)
+		} else if unicode.IsControl(r) {
+			fmt.Fprintf(&buf, "\\x%x;", r)
+		} else {
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteRune(delimiter)
+	return buf.String()
+}
+
+// stringValue returns the content of String or *MutableString.
+func stringValue(exp Expression) (string, bool) {
+	switch s := exp.(type) {
+	case String:
+		return string(s), true
+	case *MutableString:
+		return string(s.runes), true
+	default:
+		return "", false
+	}
+}
+
+func expressionToGoString(exp Expression) (string, error) {
+	s, ok := stringValue(exp)
+	if !ok {
+		return "", fmt.Errorf("%v is not a string", valueToString(exp))
+	}
+	return s, nil
+}
+
+// stringRunesCache keeps the runes of the String converted last, so indexing the same immutable string in a loop
+// doesn't convert it every time.
+var stringRunesCache struct {
+	sync.Mutex
+	s     String
+	runes []rune
+}
+
+// stringRunes returns the characters of String or *MutableString, the result must not be modified.
+func stringRunes(exp Expression) ([]rune, error) {
+	switch s := exp.(type) {
+	case *MutableString:
+		return s.runes, nil
+	case String:
+		c := &stringRunesCache
+		c.Lock()
+		defer c.Unlock()
+		if c.runes == nil || c.s != s {
+			c.s, c.runes = s, []rune(string(s))
+		}
+		return c.runes, nil
+	}
+	return nil, fmt.Errorf("%v is not a string", valueToString(exp))
+}
+
+// rangeArgs returns the [start, end) range specified by the optional arguments at index i and i+1 within length.
+func rangeArgs(args []Expression, i int, length int) (start, end int, err error) {
+	start, end = 0, length
+	if len(args) > i {
+		k, ok := args[i].(Integer)
+		if !ok || k < 0 || int(k) > length {
+			return 0, 0, fmt.Errorf("start index %v out of range", valueToString(args[i]))
+		}
+		start = int(k)
+	}
+	if len(args) > i+1 {
+		k, ok := args[i+1].(Integer)
+		if !ok || int(k) < start || int(k) > length {
+			return 0, 0, fmt.Errorf("end index %v out of range", valueToString(args[i+1]))
+		}
+		end = int(k)
+	}
+	return start, end, nil
+}
+
+// indexArg checks the argument at index i is a valid index of a sequence with length.
+func indexArg(args []Expression, i int, length int) (int, error) {
+	k, ok := args[i].(Integer)
+	if !ok || k < 0 || int(k) >= length {
+		return 0, fmt.Errorf("index %v out of range", valueToString(args[i]))
+	}
+	return int(k), nil
+}
+
+func isStringFunc(args ...Expression) (Expression, error) {
+	_, ok := stringValue(args[0])
+	return ok, nil
+}
+
+func makeStringFunc(args ...Expression) (Expression, error) {
+	k, ok := args[0].(Integer)
+	if !ok || k < 0 {
+		return UndefObj, fmt.Errorf("make-string: %v is not a valid length", valueToString(args[0]))
+	}
+	fill := Char(' ')
+	if len(args) > 1 {
+		c, err := expressionToChar(args[1])
+		if err != nil {
+			return UndefObj, err
+		}
+		fill = c
+	}
+	s := &MutableString{runes: make([]rune, k)}
+	for i := range s.runes {
+		s.runes[i] = rune(fill)
+	}
+	return s, nil
+}
+
+func stringFunc(args ...Expression) (Expression, error) {
+	chars, err := charArgs(args, false)
+	if err != nil {
+		return UndefObj, err
+	}
+	runes := make([]rune, len(chars))
+	for i, c := range chars {
+		runes[i] = rune(c)
+	}
+	return &MutableString{runes: runes}, nil
+}
+
+func stringLengthFunc(args ...Expression) (Expression, error) {
+	if s, ok := args[0].(String); ok {
+		return Integer(utf8.RuneCountInString(string(s))), nil
+	}
+	runes, err := stringRunes(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	return Integer(len(runes)), nil
+}
+
+func stringRefFunc(args ...Expression) (Expression, error) {
+	runes, err := stringRunes(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	k, err := indexArg(args, 1, len(runes))
+	if err != nil {
+		return UndefObj, fmt.Errorf("string-ref: %s", err)
+	}
+	return Char(runes[k]), nil
+}
+
+func stringSetFunc(args ...Expression) (Expression, error) {
+	s, ok := args[0].(*MutableString)
+	if !ok {
+		return UndefObj, fmt.Errorf("string-set!: %v is not a mutable string", valueToString(args[0]))
+	}
+	k, err := indexArg(args, 1, len(s.runes))
+	if err != nil {
+		return UndefObj, fmt.Errorf("string-set!: %s", err)
+	}
+	c, err := expressionToChar(args[2])
+	if err != nil {
+		return UndefObj, err
+	}
+	s.runes[k] = rune(c)
+	return UndefObj, nil
+}
+
+func stringFillFunc(args ...Expression) (Expression, error) {
+	s, ok := args[0].(*MutableString)
+	if !ok {
+		return UndefObj, fmt.Errorf("string-fill!: %v is not a mutable string", valueToString(args[0]))
+	}
+	c, err := expressionToChar(args[1])
+	if err != nil {
+		return UndefObj, err
+	}
+	start, end, err := rangeArgs(args, 2, len(s.runes))
+	if err != nil {
+		return UndefObj, fmt.Errorf("string-fill!: %s", err)
+	}
+	for i := start; i < end; i++ {
+		s.runes[i] = rune(c)
+	}
+	return UndefObj, nil
+}
+
+// substringRunes returns the runes of the string argument in the range specified by the optional arguments, the
+// result must not be modified.
+func substringRunes(name string, args []Expression) ([]rune, error) {
+	runes, err := stringRunes(args[0])
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := rangeArgs(args, 1, len(runes))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	return runes[start:end], nil
+}
+
+func substringFunc(args ...Expression) (Expression, error) {
+	runes, err := substringRunes("substring", args)
+	if err != nil {
+		return UndefObj, err
+	}
+	return &MutableString{runes: append([]rune(nil), runes...)}, nil
+}
+
+func stringCopyFunc(args ...Expression) (Expression, error) {
+	runes, err := substringRunes("string-copy", args)
+	if err != nil {
+		return UndefObj, err
+	}
+	return &MutableString{runes: append([]rune(nil), runes...)}, nil
+}
+
+func stringAppendFunc(args ...Expression) (Expression, error) {
+	var buf strings.Builder
+	for _, arg := range args {
+		s, err := expressionToGoString(arg)
+		if err != nil {
+			return UndefObj, err
+		}
+		buf.WriteString(s)
+	}
+	return NewMutableString(buf.String()), nil
+}
+
+// stringConverter returns the builtin function mapping the string argument to a new string with the convert function.
+func stringConverter(convert func(s string) string) commonFunction {
+	return func(args ...Expression) (Expression, error) {
+		s, err := expressionToGoString(args[0])
+		if err != nil {
+			return UndefObj, err
+		}
+		return NewMutableString(convert(s)), nil
+	}
+}
+
+func foldString(s string) string {
+	return strings.Map(foldRune, s)
+}
+
+func stringToListFunc(args ...Expression) (Expression, error) {
+	runes, err := substringRunes("string->list", args)
+	if err != nil {
+		return UndefObj, err
+	}
+	chars := make([]Expression, len(runes))
+	for i, r := range runes {
+		chars[i] = Char(r)
+	}
+	return listImpl(chars...)
+}
+
+func listToStringFunc(args ...Expression) (Expression, error) {
+	if !isList(args[0]) {
+		return UndefObj, fmt.Errorf("list->string: %v is not a list", valueToString(args[0]))
+	}
+	return stringFunc(extractList(args[0])...)
+}
+
+func stringToSymbolFunc(args ...Expression) (Expression, error) {
+	s, err := expressionToGoString(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	return intern(s), nil
+}
+
+func symbolToStringFunc(args ...Expression) (Expression, error) {
+	sym, err := expressionToSymbol(args[0])
+	if err != nil {
+		return UndefObj, fmt.Errorf("symbol->string: %s", err)
+	}
+	return String(sym.String()), nil
+}
+
+// stringIndex returns the index of the first character of the string equal to the char or satisfying the predicate,
+// or #f. (string-index "hello" #\l) => 2
+func stringIndex(m *machine, args ...Expression) error {
+	runes, err := stringRunes(args[0])
+	if err != nil {
+		return err
+	}
+	// the predicate may modify the string
+	runes = append([]rune(nil), runes...)
+	c, isChar := args[1].(Char)
+	var next func(m *machine, i int) error
+	next = func(m *machine, i int) error {
+		for ; i < len(runes); i++ {
+			if isChar {
+				if runes[i] == rune(c) {
+					m.ret(Integer(i))
+					return nil
+				}
+				continue
+			}
+			index := i
+			m.push(func(m *machine, matched Expression) error {
+				if IsTrue(matched) {
+					m.ret(Integer(index))
+					return nil
+				}
+				return next(m, index+1)
+			})
+			return m.apply(args[1], []Expression{Char(runes[i])})
+		}
+		m.ret(false)
+		return nil
+	}
+	return next(m, 0)
+}
+
+func stringSearchForwardFunc(args ...Expression) (Expression, error) {
+	pattern, err := expressionToGoString(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	runes, err := stringRunes(args[1])
+	if err != nil {
+		return UndefObj, err
+	}
+	start, _, err := rangeArgs(args, 2, len(runes))
+	if err != nil {
+		return UndefObj, fmt.Errorf("string-search-forward: %s", err)
+	}
+	rest := string(runes[start:])
+	i := strings.Index(rest, pattern)
+	if i < 0 {
+		return false, nil
+	}
+	return Integer(start + utf8.RuneCountInString(rest[:i])), nil
+}
+
+func stringSplitFunc(args ...Expression) (Expression, error) {
+	s, err := expressionToGoString(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	var pieces []string
+	if len(args) == 1 {
+		pieces = strings.Fields(s)
+	} else {
+		sep, ok := stringValue(args[1])
+		if c, isChar := args[1].(Char); isChar {
+			sep, ok = string(c), true
+		}
+		if !ok {
+			return UndefObj, fmt.Errorf("string-split: %v is not a char or string", valueToString(args[1]))
+		}
+		pieces = strings.Split(s, sep)
+	}
+	ret := make([]Expression, len(pieces))
+	for i, p := range pieces {
+		ret[i] = NewMutableString(p)
+	}
+	return listImpl(ret...)
+}
+
+func stringJoinFunc(args ...Expression) (Expression, error) {
+	if !isList(args[0]) {
+		return UndefObj, fmt.Errorf("string-join: %v is not a list", valueToString(args[0]))
+	}
+	delimiter := " "
+	if len(args) > 1 {
+		d, err := expressionToGoString(args[1])
+		if err != nil {
+			return UndefObj, err
+		}
+		delimiter = d
+	}
+	var pieces []string
+	for _, e := range extractList(args[0]) {
+		s, err := expressionToGoString(e)
+		if err != nil {
+			return UndefObj, err
+		}
+		pieces = append(pieces, s)
+	}
+	return NewMutableString(strings.Join(pieces, delimiter)), nil
+}
+
+// compareStrings returns the builtin function comparing each adjacent pair of the string arguments.
+func compareStrings(foldCase bool, test func(c int) bool) commonFunction {
+	return func(args ...Expression) (Expression, error) {
+		strs := make([]string, len(args))
+		for i, arg := range args {
+			s, err := expressionToGoString(arg)
+			if err != nil {
+				return UndefObj, err
+			}
+			if foldCase {
+				s = foldString(s)
+			}
+			strs[i] = s
+		}
+		for i := 0; i < len(strs)-1; i++ {
+			if !test(strings.Compare(strs[i], strs[i+1])) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+func trimSpace(s string) string {
+	return strings.TrimFunc(s, unicode.IsSpace)
+}
+
+func trimLeftSpace(s string) string {
+	return strings.TrimLeftFunc(s, unicode.IsSpace)
+}
+
+func trimRightSpace(s string) string {
+	return strings.TrimRightFunc(s, unicode.IsSpace)
+}
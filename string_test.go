@@ -0,0 +1,134 @@
+package goscheme
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStringFunctions(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected Expression
+	}{
+		{`(string? "abc")`, true},
+		{`(string? (make-string 2))`, true},
+		{`(string? #\a)`, false},
+		{`(string-length "abc")`, Integer(3)},
+		{`(string-length "λμν")`, Integer(3)},
+		{`(string-length "")`, Integer(0)},
+		{`(string #\a #\λ)`, NewMutableString("aλ")},
+		{`(make-string 3 #\x)`, NewMutableString("xxx")},
+		{`(substring "hello world" 6)`, NewMutableString("world")},
+		{`(substring "λμνξ" 1 3)`, NewMutableString("μν")},
+		{`(string-append "foo" "bar" (string-copy "baz"))`, NewMutableString("foobarbaz")},
+		{`(string-append)`, NewMutableString("")},
+		{`(string-copy "hello" 1 3)`, NewMutableString("el")},
+		{`(define s (string-copy "abc")) (string-set! s 1 #\λ) s`, NewMutableString("aλc")},
+		{`(define s (make-string 3 #\a)) (string-fill! s #\b 1) s`, NewMutableString("abb")},
+		{`(string-upcase "Hello")`, NewMutableString("HELLO")},
+		{`(string-downcase "Hello")`, NewMutableString("hello")},
+		{`(string->list "aλ")`, &Pair{Char('a'), &Pair{Char('λ'), NilObj}}},
+		{`(string->list "abc" 1)`, &Pair{Char('b'), &Pair{Char('c'), NilObj}}},
+		{`(list->string (list #\a #\b))`, NewMutableString("ab")},
+		{`(string->symbol "abc")`, Symbol("abc")},
+		{`(symbol->string 'abc)`, String("abc")},
+		{`(string-index "hello" #\l)`, Integer(2)},
+		{`(string-index "hello" char-upper-case?)`, false},
+		{`(string-index "λμν" #\ν)`, Integer(2)},
+		{`(string-search-forward "lo" "hello hello" 0)`, Integer(3)},
+		{`(string-search-forward "lo" "hello hello" 4)`, Integer(9)},
+		{`(string-search-forward "λ" "μνλ")`, Integer(2)},
+		{`(string-search-forward "x" "hello")`, false},
+		{`(string-split "a,b,,c" #\,)`,
+			&Pair{NewMutableString("a"), &Pair{NewMutableString("b"), &Pair{NewMutableString(""), &Pair{NewMutableString("c"), NilObj}}}}},
+		{`(string-split "  a  b ")`, &Pair{NewMutableString("a"), &Pair{NewMutableString("b"), NilObj}}},
+		{`(string-split "a::b" "::")`, &Pair{NewMutableString("a"), &Pair{NewMutableString("b"), NilObj}}},
+		{`(string-join '("a" "b" "c"))`, NewMutableString("a b c")},
+		{`(string-join '("a" "b" "c") ", ")`, NewMutableString("a, b, c")},
+		{`(string-trim "  a b  ")`, NewMutableString("a b")},
+		{`(string-trim-left "  a ")`, NewMutableString("a ")},
+		{`(string-trim-right "  a ")`, NewMutableString("  a")},
+		{`(string=? "abc" "abc" (string-copy "abc"))`, true},
+		{`(string=? "abc" "abd")`, false},
+		{`(string<? "abc" "abd" "b")`, true},
+		{`(string>? "b" "a")`, true},
+		{`(string-ci=? "Hello" "hELLO")`, true},
+		{`(string-map char-upcase "abc")`, NewMutableString("ABC")},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, ret, c.input)
+	}
+	for _, input := range []string{`(string-set! "abc" 0 #\x)`, `(string-set! (make-string 1) 1 #\x)`,
+		`(substring "abc" 2 1)`, `(string-length 'a)`, `(symbol->string "a")`} {
+		_, err := EvalAll(strToToken(input), env)
+		assert.NotNil(t, err, input)
+	}
+}
+
+func TestMutableStrings(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{`(define s (string-append "a" "b")) (string-set! s 0 #\z) s`, `"zb"`},
+		{`(define s (substring "hello" 0 2)) (string-fill! s #\x) s`, `"xx"`},
+		{`(define s (string #\a #\b)) (string-set! s 1 #\λ) s`, `"aλ"`},
+		{`(define s (list->string (list #\a #\b))) (string-fill! s #\c 1) s`, `"ac"`},
+		{`(define s (string-upcase "ab")) (string-set! s 0 #\x) s`, `"xB"`},
+		{`(define s (string-downcase "AB")) (string-set! s 1 #\x) s`, `"ax"`},
+		{`(define s (string-foldcase "AB")) (string-set! s 1 #\x) s`, `"ax"`},
+		{`(define s (string-trim " ab ")) (string-set! s 1 #\x) s`, `"ax"`},
+		{`(define s (string-join '("a" "b") "-")) (string-set! s 1 #\+) s`, `"a+b"`},
+		{`(define s (car (string-split "ab cd"))) (string-set! s 0 #\x) s`, `"xb"`},
+		{`(define s (number->string 42)) (string-set! s 0 #\1) s`, `"12"`},
+		{`(define s (utf8->string #u8(97 98))) (string-set! s 0 #\z) s`, `"zb"`},
+		{`(define s (string-map char-upcase "ab")) (string-set! s 0 #\z) s`, `"zB"`},
+		// each call allocates a new string
+		{`(define (f) (string-append "a" "b")) (string-set! (f) 0 #\z) (f)`, `"ab"`},
+		{`(string-index "hello" (lambda (c) (char=? c #\o)))`, "4"},
+		{`(string-index (string-copy "hello") #\z)`, "#f"},
+		// the indexed accessors read the characters of the strings directly
+		{`(define s (make-string 3 #\a)) (define t (substring s 0 2)) (string-set! t 0 #\b) (list s t)`, `("aaa" "ba")`},
+		{`(define s (string-copy "aλc")) (string-set! s 1 #\b) (list (string-ref s 1) (string-ref "aλc" 1) (string-length "aλc") (string-ref "xyz" 2))`,
+			`(#\b #\λ 3 #\z)`},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+	for _, input := range []string{`(string-set! (symbol->string 'ab) 0 #\x)`, `(string-index 'a #\a)`} {
+		_, err := EvalAll(strToToken(input), env)
+		assert.NotNil(t, err, input)
+	}
+}
+
+func TestWriteStrings(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{`(string #\" #\\ #\a)`, `"\"\\a"`},
+		{`(string #\newline #\tab #\return (integer->char 7) (integer->char 8))`, `"\n\t\r\a\b"`},
+		{`(string (integer->char 0) (integer->char 127) #\λ)`, `"\x0;\x7f;λ"`},
+		{`"a\x41;\"b"`, `"aA\"b"`},
+		{`(list "x\"y" (vector "\\"))`, `("x\"y" #("\\"))`},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+		// the written string is read back to the same string
+		if s, ok := stringValue(ret); ok {
+			read, err := EvalAll(strToToken(c.expected), env)
+			assert.Nil(t, err, c.expected)
+			readString, _ := stringValue(read)
+			assert.Equal(t, s, readString, c.expected)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package goscheme
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Symbol is used as both the variable name of the source and the symbol value of quote, two symbols are eq? when they
+// have the same name. The uninterned symbols created by gensym start with uninternedMarker, which the reader and
+// string->symbol never produce.
+type Symbol string
+
+// String returns the name of the symbol, the uninterned symbol is printed without its unique number.
+func (s Symbol) String() string {
+	name := string(s)
+	if !strings.HasPrefix(name, uninternedMarker) {
+		return name
+	}
+	if strings.HasPrefix(name, uninternedMarker+uninternedMarker) {
+		return name[len(uninternedMarker):]
+	}
+	return name[strings.Index(name, " ")+1:]
+}
+
+// writeSymbol returns the external representation of the symbol, the name is written between vertical lines such as
+// |hello world| when it would not be read back as the same symbol or contains a vertical line.
+func writeSymbol(s Symbol) string {
+	name := s.String()
+	tokens := NewTokenizerFromString(name).Tokens()
+	if len(tokens) == 1 && tokens[0] == name && !strings.ContainsRune(name, '|') {
+		if exps, err := Parse(&tokens); err == nil && len(exps) == 1 && exps[0] == intern(name) {
+			return name
+		}
+	}
+	return quoteText(name, '|')
+}
+
+// IsSymbolValue checks whether the expression value is a Symbol.
+func IsSymbolValue(exp Expression) bool {
+	_, ok := exp.(Symbol)
+	return ok
+}
+
+// uninternedMarker starts the uninterned symbols, which are followed by a unique number and the name such as
+// "\x002 tmp".
+const uninternedMarker = "\x00"
+
+// intern returns the symbol of the name read from the source or converted from a string. The names starting with
+// uninternedMarker are escaped by another marker, so they never collide with the uninterned symbols.
+func intern(name string) Symbol {
+	if strings.HasPrefix(name, uninternedMarker) {
+		return Symbol(uninternedMarker + name)
+	}
+	return Symbol(name)
+}
+
+var gensymCount int64
+
+// gensym creates the uninterned symbol named by the prefix and a counter such as g12.
+func gensym(prefix string) Symbol {
+	n := atomic.AddInt64(&gensymCount, 1)
+	return Symbol(fmt.Sprintf("%s%d %s%d", uninternedMarker, n, prefix, n))
+}
+
+func expressionToSymbol(exp Expression) (Symbol, error) {
+	sym, ok := exp.(Symbol)
+	if !ok {
+		return "", fmt.Errorf("%v is not a symbol", valueToString(exp))
+	}
+	return sym, nil
+}
+
+func isSymbolFunc(args ...Expression) (Expression, error) {
+	return IsSymbolValue(args[0]), nil
+}
+
+// gensymFunc creates the uninterned symbol, the optional argument is the string or symbol prefix of the name.
+func gensymFunc(args ...Expression) (Expression, error) {
+	prefix := "g"
+	if len(args) > 0 {
+		switch v := args[0].(type) {
+		case Symbol:
+			prefix = v.String()
+		default:
+			s, err := expressionToGoString(v)
+			if err != nil {
+				return UndefObj, fmt.Errorf("gensym: %v is not a string or symbol", valueToString(v))
+			}
+			prefix = s
+		}
+	}
+	return gensym(prefix), nil
+}
+
+func symbolLessFunc(args ...Expression) (Expression, error) {
+	for i := range args {
+		if _, err := expressionToSymbol(args[i]); err != nil {
+			return UndefObj, fmt.Errorf("symbol<?: %s", err)
+		}
+	}
+	for i := 0; i+1 < len(args); i++ {
+		if args[i].(Symbol).String() >= args[i+1].(Symbol).String() {
+			return false, nil
+		}
+	}
+	return true, nil
+}
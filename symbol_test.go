@@ -0,0 +1,86 @@
+package goscheme
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestSymbols(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{`(equal? 'a (string->symbol "a"))`, "#t"},
+		{`(list (symbol? 'a) (symbol? "a") (symbol? (gensym)) (symbol? (car '(x))))`, "(#t #f #t #t)"},
+		{"(equal? (gensym) (gensym))", "#f"},
+		{`(let ((g (generate-uninterned-symbol "tmp"))) (list (equal? g g) (equal? g (string->symbol (symbol->string g)))))`, "(#t #f)"},
+		{"(list (symbol<? 'a 'b 'c) (symbol<? 'b 'a) (symbol<? 'a 'a) (symbol<? 'a))", "(#t #f #f #t)"},
+		{"(eval (list '+ 1 2))", "3"},
+		{`(symbol->string (eval (list 'quote (string->symbol "a b"))))`, `"a b"`},
+		{"(let ((g (gensym))) (eval (list 'let (list (list g 1)) (list '+ g 1))))", "2"},
+		{"(defmacro my-or2 (a b) (let ((v (gensym))) (list 'let (list (list v a)) (list 'if v v b))))" +
+			"(define v 5) (my-or2 #f v)", "5"},
+		{"(let ((x 'sym)) x)", "sym"},
+		{"`(a ,'b)", "(a b)"},
+		{`(let ((s (string (integer->char 0) #\1 #\space #\a))) (list (equal? (symbol->string (string->symbol s)) s) (eq? (string->symbol s) (string->symbol s))))`,
+			"(#t #t)"},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+
+	ret, err := EvalAll(strToToken(`(gensym 'tmp)`), env)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(valueToString(ret), "tmp"))
+	assert.NotContains(t, valueToString(ret), uninternedMarker)
+
+	// the uninterned symbols can't be forged from their representation
+	g := gensym("a")
+	env.Set("forged-g", g)
+	env.Set("forged-name", String(g))
+	ret, err = EvalAll(strToToken(`(list (eq? forged-g (string->symbol forged-name)) (symbol->string forged-g))`), env)
+	assert.Nil(t, err)
+	assert.Equal(t, fmt.Sprintf(`(#f "%s")`, g.String()), valueToString(ret))
+
+	for _, input := range []string{`(symbol<? 'a "b")`, "(symbol->string 1)", "(gensym 1)"} {
+		_, err := EvalAll(strToToken(input), env)
+		assert.NotNil(t, err, input)
+	}
+}
+
+func TestWriteSymbols(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"'abc", "abc"},
+		{"'|a b|", "|a b|"},
+		{`(string->symbol "hello world")`, "|hello world|"},
+		{`(list (string->symbol "1") (string->symbol "") (string->symbol "a|b") (string->symbol "#t"))`, `(|1| || |a\|b| |#t|)`},
+		{`(string->symbol "x\ny")`, `|x\ny|`},
+		{`(string->symbol "\"x\"")`, `|"x"|`},
+		{`(symbol->string '|a\x41;\|b|)`, `"aA|b"`},
+		{"(eq? '|abc| 'abc)", "#t"},
+		{"(symbol? '|1|)", "#t"},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+
+	// the written symbols are read back to the same symbols
+	input := `(string->symbol "hello world") (string->symbol "1") (string->symbol "a|b") (string->symbol "(")`
+	written, err := EvalAll(strToToken("(list "+input+")"), env)
+	assert.Nil(t, err)
+	standardInput = NewTokenizerFromString(valueToString(written))
+	ret, err := EvalAll(strToToken("(equal? (read) (list "+input+"))"), env)
+	assert.Nil(t, err)
+	assert.Equal(t, "#t", valueToString(ret))
+}
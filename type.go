@@ -2,8 +2,8 @@ package goscheme
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
 )
@@ -11,15 +11,13 @@ import (
 // Expression represent the parsed tokens of scheme syntax tree or the low level builtin types.
 type Expression interface{}
 
-// Number in scheme.
-type Number float64
-
 // String represents string in scheme.
 type String string
 
-// String return the string to display wrapping the low level string with quotes.
+// String return the string to write wrapping the low level string with quotes, the quotes, backslashes and control
+// characters are escaped so it can be read back.
 func (s String) String() string {
-	return "\"" + string(s) + "\""
+	return quoteString(string(s))
 }
 
 // SyntaxMap contains all defined scheme syntax.
@@ -28,10 +26,15 @@ var SyntaxMap = make(map[string]*Syntax)
 // SyntaxFunc specified the common func format for Syntax
 type SyntaxFunc func(args []Expression, env *Env) (Expression, error)
 
+// syntaxStep evaluates the syntax on the machine, it sets the next state of the machine instead of returning the
+// value like SyntaxFunc, so the sub expressions are evaluated with the continuation of the machine.
+type syntaxStep func(m *machine, args []Expression, env *Env) error
+
 // Syntax wrap a syntax and give method to eval it.
 type Syntax struct {
 	fn   SyntaxFunc
 	name string
+	step syntaxStep
 }
 
 // String return the string to display representing the syntax.
@@ -41,39 +44,78 @@ func (s *Syntax) String() string {
 
 // Eval runs the syntax and return the result.
 func (s *Syntax) Eval(args []Expression, env *Env) (Expression, error) {
-	return s.fn(args, env)
+	if s.step == nil {
+		return s.fn(args, env)
+	}
+	m := &machine{}
+	m.push(func(m *machine, _ Expression) error {
+		return s.step(m, args, env)
+	})
+	m.ret(UndefObj)
+	return m.run()
+}
+
+// apply evaluates the syntax on the machine, the value of SyntaxFunc is returned as it is, since the symbols and lists
+// returned by quote are data.
+func (s *Syntax) apply(m *machine, args []Expression, env *Env) error {
+	if s.step != nil {
+		return s.step(m, args, env)
+	}
+	value, err := s.fn(args, env)
+	if err != nil {
+		return err
+	}
+	m.ret(value)
+	return nil
 }
 
 // NewSyntax construct a Syntax with custom name and SyntaxFunc
 func NewSyntax(name string, fn SyntaxFunc) *Syntax {
-	return &Syntax{fn, name}
+	return &Syntax{fn: fn, name: name}
+}
+
+func newStepSyntax(name string, step syntaxStep) *Syntax {
+	return &Syntax{name: name, step: step}
 }
 
 func initSyntax() {
-	SyntaxMap["define"] = NewSyntax("define", evalDefine)
-	SyntaxMap["eval"] = NewSyntax("eval", evalEval)
-	SyntaxMap["apply"] = NewSyntax("apply", evalApply)
-	SyntaxMap["if"] = NewSyntax("if", evalIf)
-	SyntaxMap["cond"] = NewSyntax("cond", evalCond)
-	SyntaxMap["begin"] = NewSyntax("begin", evalBegin)
+	SyntaxMap["define"] = newStepSyntax("define", evalDefine)
+	SyntaxMap["eval"] = newStepSyntax("eval", evalEval)
+	SyntaxMap["apply"] = newStepSyntax("apply", evalApply)
+	SyntaxMap["if"] = newStepSyntax("if", evalIf)
+	SyntaxMap["cond"] = newStepSyntax("cond", evalCond)
+	SyntaxMap["case"] = newStepSyntax("case", evalCase)
+	SyntaxMap["when"] = newStepSyntax("when", conditionalBody("when", true))
+	SyntaxMap["unless"] = newStepSyntax("unless", conditionalBody("unless", false))
+	SyntaxMap["do"] = newStepSyntax("do", evalDo)
+	SyntaxMap["begin"] = newStepSyntax("begin", evalBegin)
 	SyntaxMap["lambda"] = NewSyntax("lambda", evalLambda)
-	SyntaxMap["load"] = NewSyntax("load", evalLoad)
+	SyntaxMap["case-lambda"] = NewSyntax("case-lambda", evalCaseLambda)
+	SyntaxMap["load"] = newStepSyntax("load", evalLoad)
 	SyntaxMap["delay"] = NewSyntax("delay", evalDelay)
-	SyntaxMap["and"] = NewSyntax("and", evalAnd)
-	SyntaxMap["or"] = NewSyntax("and", evalOr)
-	SyntaxMap["let"] = NewSyntax("let", evalLet)
-	SyntaxMap["let*"] = NewSyntax("let*", evalL2RLet)
-	SyntaxMap["letrec"] = NewSyntax("letrec", evalLetRec)
+	SyntaxMap["and"] = newStepSyntax("and", evalAnd)
+	SyntaxMap["or"] = newStepSyntax("or", evalOr)
+	SyntaxMap["let"] = newStepSyntax("let", evalLet)
+	SyntaxMap["let*"] = newStepSyntax("let*", evalL2RLet)
+	SyntaxMap["letrec"] = newStepSyntax("letrec", evalLetRec)
 	SyntaxMap["quote"] = NewSyntax("quote", evalQuote)
-	SyntaxMap["set!"] = NewSyntax("set!", evalSet)
+	SyntaxMap["quasiquote"] = newStepSyntax("quasiquote", evalQuasiquote)
+	SyntaxMap["set!"] = newStepSyntax("set!", evalSet)
+	SyntaxMap["define-record-type"] = NewSyntax("define-record-type", evalDefineRecordType)
+	SyntaxMap["define-syntax"] = newStepSyntax("define-syntax", evalDefineSyntax)
+	SyntaxMap["let-syntax"] = newStepSyntax("let-syntax", evalLetSyntax)
+	SyntaxMap["letrec-syntax"] = newStepSyntax("letrec-syntax", evalLetrecSyntax)
+	SyntaxMap["syntax-rules"] = NewSyntax("syntax-rules", evalSyntaxRules)
+	SyntaxMap["define-macro"] = NewSyntax("define-macro", evalDefineMacro)
+	SyntaxMap["defmacro"] = NewSyntax("defmacro", evalDefmacro)
+	SyntaxMap["let/ec"] = newStepSyntax("let/ec", evalLetEC)
+	SyntaxMap["guard"] = newStepSyntax("guard", evalGuard)
+	SyntaxMap["let-values"] = newStepSyntax("let-values", evalLetValues)
+	SyntaxMap["let*-values"] = newStepSyntax("let*-values", evalL2RLetValues)
+	SyntaxMap["define-values"] = newStepSyntax("define-values", evalDefineValues)
+	SyntaxMap["receive"] = newStepSyntax("receive", evalReceive)
 }
 
-// Symbol represents the variable name in scheme.
-type Symbol string
-
-// Quote type in scheme
-type Quote string
-
 type commonFunction func(args ...Expression) (Expression, error)
 
 // Function represents the basic scheme function in pure go.
@@ -149,20 +191,36 @@ func (t *Thunk) Value() (Expression, error) {
 	if t.ret != nil {
 		return t.ret, nil
 	}
-	value, err := Eval(t.Exp, t.Env)
-	if err != nil {
-		return UndefObj, err
-	}
-	switch t2 := value.(type) {
-	case *Thunk:
-		value, err = t2.Value()
-	default:
+	return applyProcedure(controlForce, t)
+}
+
+// controlForce is the force procedure, which evaluates the thunk on the machine.
+var controlForce = NewControlFunction("force", force, 1, 1)
+
+// force evaluates the expression of the thunk and caches the value, the thunk returned by the expression is forced too.
+// The values other than thunks are returned as they are.
+func force(m *machine, args ...Expression) error {
+	t, ok := args[0].(*Thunk)
+	if !ok {
+		m.ret(args[0])
+		return nil
 	}
-	if err != nil {
-		return UndefObj, err
+	if t.ret != nil {
+		m.ret(t.ret)
+		return nil
 	}
-	t.ret = value
-	return t.ret, nil
+	m.evalThen(t.Exp, t.Env, func(m *machine, value Expression) error {
+		m.push(func(m *machine, value Expression) error {
+			// the thunk may be forced by its own expression, the value computed first is kept
+			if t.ret == nil {
+				t.ret = value
+			}
+			m.ret(t.ret)
+			return nil
+		})
+		return force(m, value)
+	})
+	return nil
 }
 
 // IsThunk checks whether an expression is a thunk and return the result
@@ -202,6 +260,17 @@ func (n NilType) String() string {
 // NilObj is the common object of NilType
 var NilObj = NilType{}
 
+// EOFType represents the end of file object returned by read at the end of the input.
+type EOFType struct{}
+
+// String returns the string representing EOFType.
+func (e EOFType) String() string {
+	return "#<eof>"
+}
+
+// EOFObj is the common object of EOFType
+var EOFObj = EOFType{}
+
 // Undef represents undefined expression value.
 type Undef struct{}
 
@@ -231,11 +300,8 @@ var UndefObj = Undef{}
 func IsNumber(exp Expression) bool {
 	switch v := exp.(type) {
 	case string:
-		_, err := strconv.ParseFloat(v, 64)
-		if err != nil {
-			return false
-		}
-		return true
+		_, ok := parseNumber(v, 10)
+		return ok
 	case Number:
 		return true
 	default:
@@ -247,12 +313,8 @@ func IsNumber(exp Expression) bool {
 func IsString(exp Expression) bool {
 	switch v := exp.(type) {
 	case string:
-		ok, err := regexp.MatchString("\"(.|[\\r\\n])*\"", v)
-		if ok && err == nil {
-			return true
-		}
-		return false
-	case String:
+		return len(v) >= 2 && strings.HasPrefix(v, "\"") && strings.HasSuffix(v, "\"")
+	case String, *MutableString:
 		return true
 	default:
 		return false
@@ -265,7 +327,7 @@ func IsSyntaxExpression(exp Expression) bool {
 	if !ok {
 		return false
 	}
-	operator := ops[0]
+	operator := symbolName(ops[0])
 
 	for key := range SyntaxMap {
 		if key == operator {
@@ -275,19 +337,14 @@ func IsSyntaxExpression(exp Expression) bool {
 	return false
 }
 
-// IsSymbol checks whether the expression is Symbol.
+// IsSymbol checks whether the expression is a symbol of the source, a Symbol or the identifier inserted by macro.
 func IsSymbol(expression Expression) bool {
-	_, ok := expression.([]Expression)
-	if ok {
-		return false
-	}
-	if _, ok := expression.(string); !ok {
-		return false
-	}
-	if IsNumber(expression) || IsString(expression) || IsBoolean(expression) {
+	switch expression.(type) {
+	case Symbol, *identifier:
+		return true
+	default:
 		return false
 	}
-	return true
 }
 
 // IsBoolean return true if the expression represents bool.
@@ -339,22 +396,17 @@ func IsPair(obj Expression) bool {
 
 // LambdaProcess wraps the body and env of a lambda expression
 type LambdaProcess struct {
-	params []Symbol
-	body   []Expression // expressions of the lambda process
-	env    *Env
+	formals
+	body []Expression // expressions of the lambda process
+	env  *Env
 }
 
 // String implements the stringer interface
 func (lambda *LambdaProcess) String() string {
 	var buf bytes.Buffer
-	buf.WriteString("(lambda (")
-	for i, k := range lambda.params {
-		buf.WriteString(string(k))
-		if i != len(k)-1 {
-			buf.WriteString(" ")
-		}
-	}
-	buf.WriteString(") ")
+	buf.WriteString("(lambda ")
+	buf.WriteString(lambda.formals.String())
+	buf.WriteString(" ")
 	buf.WriteString(concatLambdaBodyToString(lambda.body))
 	buf.WriteString(")")
 	return buf.String()
@@ -390,6 +442,33 @@ func concatLambdaBodyToString(expressions []Expression) string {
 	return buf.String()
 }
 
+// bind creates the environment to execute the body with the required and rest parameters bound to args, the optional
+// and keyword parameters are bound by the machine since their defaults need evaluation.
+func (lambda *LambdaProcess) bind(args []Expression) (*Env, error) {
+	if !lambda.accepts(len(args)) {
+		return nil, lambda.arityError(len(args))
+	}
+	newEnv := &Env{outer: lambda.env, frame: make(map[Symbol]Expression)}
+	for i, param := range lambda.params {
+		newEnv.Set(param, args[i])
+	}
+	if lambda.rest != "" {
+		list, _ := listImpl(args[len(lambda.params):]...)
+		newEnv.Set(lambda.rest, list)
+	}
+	return newEnv, nil
+}
+
+func (lambda *LambdaProcess) arityError(count int) error {
+	required := strconv.Itoa(len(lambda.params))
+	if len(lambda.optionals) > 0 && lambda.rest == "" && len(lambda.keys) == 0 {
+		required += " to " + strconv.Itoa(len(lambda.params)+len(lambda.optionals))
+	} else if lambda.rest != "" || len(lambda.keys) > 0 || len(lambda.optionals) > 0 {
+		required = "at least " + required
+	}
+	return errors.New(fmt.Sprintf("%v\n", lambda.String()) + "require " + required + " but " + strconv.Itoa(count) + " provide")
+}
+
 // Body returns the expressions of body.
 func (lambda *LambdaProcess) Body() Expression {
 	if len(lambda.body) == 1 {
@@ -428,31 +507,145 @@ func (p *Pair) IsList() bool {
 
 // String returns the string representing the *Pair.
 func (p *Pair) String() string {
+	return writeDatum(p)
+}
+
+// datumWriter writes the external representation of the values. The pairs, vectors and records referring back to
+// themselves are written with datum labels such as #0=(a . #0#), so the circular values are written in finite text.
+type datumWriter struct {
+	buf strings.Builder
+	// states marks the compound values being scanned with 1 and the scanned ones with 2
+	states map[Expression]int
+	// labels holds the labels of the compound values in cycles, -1 until the label is written
+	labels map[Expression]int
+	count  int
+}
+
+// writeDatum returns the external representation of exp with the cycles labelled.
+func writeDatum(exp Expression) string {
+	w := &datumWriter{states: make(map[Expression]int), labels: make(map[Expression]int)}
+	w.scan(exp)
+	w.write(exp)
+	return w.buf.String()
+}
+
+// enter marks the compound value as being scanned and reports whether it is met for the first time, meeting the
+// value being scanned again means it is in a cycle.
+func (w *datumWriter) enter(exp Expression) bool {
+	switch w.states[exp] {
+	case 0:
+		w.states[exp] = 1
+		return true
+	case 1:
+		w.labels[exp] = -1
+	}
+	return false
+}
 
-	currentPair := p
+// scan finds the compound values in cycles.
+func (w *datumWriter) scan(exp Expression) {
+	switch v := exp.(type) {
+	case *Pair:
+		// walk the cdr in the loop so long lists don't grow the go stack
+		var chain []*Pair
+		for w.enter(v) {
+			chain = append(chain, v)
+			w.scan(v.Car)
+			next, ok := v.Cdr.(*Pair)
+			if !ok {
+				w.scan(v.Cdr)
+				break
+			}
+			v = next
+		}
+		for _, p := range chain {
+			w.states[p] = 2
+		}
+	case *Vector:
+		if w.enter(v) {
+			for _, e := range v.Elements {
+				w.scan(e)
+			}
+			w.states[v] = 2
+		}
+	case *Record:
+		if w.enter(v) {
+			for _, e := range v.values {
+				w.scan(e)
+			}
+			w.states[v] = 2
+		}
+	}
+}
 
-	var strSlices []string
+// writeLabel writes the label of the compound value in a cycle, it returns true when the value is already written
+// so only the reference to the label is needed.
+func (w *datumWriter) writeLabel(exp Expression) bool {
+	label, ok := w.labels[exp]
+	if !ok {
+		return false
+	}
+	if label >= 0 {
+		fmt.Fprintf(&w.buf, "#%d#", label)
+		return true
+	}
+	w.labels[exp] = w.count
+	fmt.Fprintf(&w.buf, "#%d=", w.count)
+	w.count++
+	return false
+}
 
-	for !currentPair.IsNull() {
-		if IsPair(currentPair.Car) {
-			strSlices = append(strSlices, currentPair.Car.(*Pair).String())
-		} else {
-			strSlices = append(strSlices, fmt.Sprintf("%v", currentPair.Car))
+func (w *datumWriter) write(exp Expression) {
+	switch v := exp.(type) {
+	case *Pair:
+		if v.IsNull() {
+			w.buf.WriteString("()")
+			return
 		}
-
-		if IsPair(currentPair.Cdr) {
-			currentPair = currentPair.Cdr.(*Pair)
-		} else {
-			if IsNilObj(currentPair.Cdr) {
+		if w.writeLabel(v) {
+			return
+		}
+		w.buf.WriteString("(")
+		w.write(v.Car)
+		cdr := v.Cdr
+		for !IsNullExp(cdr) {
+			p, ok := cdr.(*Pair)
+			if _, labelled := w.labels[cdr]; !ok || labelled {
+				w.buf.WriteString(" . ")
+				w.write(cdr)
 				break
 			}
-			strSlices = append(strSlices, ".")
-			strSlices = append(strSlices, fmt.Sprintf("%v", currentPair.Cdr))
-			break
+			w.buf.WriteString(" ")
+			w.write(p.Car)
+			cdr = p.Cdr
+		}
+		w.buf.WriteString(")")
+	case *Vector:
+		if w.writeLabel(v) {
+			return
+		}
+		w.buf.WriteString("#(")
+		for i, e := range v.Elements {
+			if i > 0 {
+				w.buf.WriteString(" ")
+			}
+			w.write(e)
+		}
+		w.buf.WriteString(")")
+	case *Record:
+		if w.writeLabel(v) {
+			return
 		}
+		w.buf.WriteString("#<record ")
+		w.buf.WriteString(v.recordType.name)
+		for i, field := range v.recordType.fields {
+			fmt.Fprintf(&w.buf, " %s=", field)
+			w.write(v.values[i])
+		}
+		w.buf.WriteString(">")
+	default:
+		w.buf.WriteString(valueToString(exp))
 	}
-
-	return "(" + strings.Join(strSlices, " ") + ")"
 }
 
 // check the result should print in console
@@ -478,6 +671,8 @@ func valueToString(exp Expression) string {
 		if v {
 			return "#t"
 		}
+	case Symbol:
+		return writeSymbol(v)
 	default:
 		return fmt.Sprintf("%v", exp)
 	}
@@ -487,8 +682,10 @@ func valueToString(exp Expression) string {
 // IsPrimitiveExpression checks whether the expressions value is the primitive types.
 func IsPrimitiveExpression(exp Expression) bool {
 	if IsNullExp(exp) || IsUndefObj(exp) ||
-		IsQuote(exp) || IsNumber(exp) ||
-		IsBoolean(exp) || IsString(exp) ||
+		IsNumber(exp) ||
+		IsBoolean(exp) || IsString(exp) || IsChar(exp) ||
+		IsVector(exp) || IsBytevector(exp) || IsHashTable(exp) || IsRecord(exp) || IsMacro(exp) || IsLispMacro(exp) ||
+		IsErrorObject(exp) || IsContinuation(exp) || IsMultipleValues(exp) || IsKeywordObject(exp) || IsCaseLambda(exp) ||
 		IsThunk(exp) || IsPair(exp) ||
 		isList(exp) || IsLambdaType(exp) {
 		return true
@@ -496,12 +693,6 @@ func IsPrimitiveExpression(exp Expression) bool {
 	return false
 }
 
-// IsQuote check whether the value is Quote.
-func IsQuote(exp Expression) bool {
-	_, ok := exp.(Quote)
-	return ok
-}
-
 // IsNullExp checks whether the expression represents Null(nil, NilType, blank list, blank expression).
 func IsNullExp(exp Expression) bool {
 	if exp == nil {
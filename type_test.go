@@ -42,6 +42,26 @@ func TestPair_String(t *testing.T) {
 	}
 }
 
+func TestWriteCircular(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"(define l (list 1 2)) (set-cdr! (cdr l) l) l", "#0=(1 2 . #0#)"},
+		{"(define p (list 1)) (set-car! p p) p", "#0=(#0#)"},
+		{"(define v (vector 1 2)) (vector-set! v 1 v) v", "#0=#(1 #0#)"},
+		{"(define l (list 1 (vector 2))) (vector-set! (car (cdr l)) 0 l) (list l l)", "(#0=(1 #(#0#)) #0#)"},
+		{"(define x (list 1)) (list x x)", "((1) (1))"},
+		{"(define l (list 1 2 3)) (set-cdr! (cdr (cdr l)) (cdr l)) l", "(1 . #0=(2 3 . #0#))"},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+}
+
 func TestIsString(t *testing.T) {
 	assert.Equal(t, true, IsString("\"sdfsdf\""))
 	assert.Equal(t, true, IsString("\"sdfdsf\n\""))
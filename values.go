@@ -0,0 +1,175 @@
+package goscheme
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MultipleValues holds the results of values except exactly one value, which is returned as itself.
+type MultipleValues []Expression
+
+// String returns the values separated by space such as #<values 1 2>, REPL prints each of them on its own line with
+// resultLines instead.
+func (v MultipleValues) String() string {
+	parts := make([]string, len(v)+1)
+	parts[0] = "#<values"
+	for i, e := range v {
+		parts[i+1] = valueToString(e)
+	}
+	return strings.Join(parts, " ") + ">"
+}
+
+// IsMultipleValues checks whether the expression value is MultipleValues.
+func IsMultipleValues(exp Expression) bool {
+	_, ok := exp.(MultipleValues)
+	return ok
+}
+
+// makeValues returns the single value itself, otherwise the MultipleValues.
+func makeValues(values ...Expression) Expression {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return append(MultipleValues{}, values...)
+}
+
+// valuesToSlice returns the values of the expression value.
+func valuesToSlice(exp Expression) []Expression {
+	if v, ok := exp.(MultipleValues); ok {
+		return v
+	}
+	return []Expression{exp}
+}
+
+func valuesFunc(args ...Expression) (Expression, error) {
+	return makeValues(args...), nil
+}
+
+// callWithValues calls the consumer with the values returned by the producer.
+func callWithValues(m *machine, args ...Expression) error {
+	consumer := args[1]
+	m.push(func(m *machine, values Expression) error {
+		return m.apply(consumer, valuesToSlice(values))
+	})
+	return m.apply(args[0], nil)
+}
+
+// valuesBinding is the binding ((a b . rest) init) of let-values.
+type valuesBinding struct {
+	params []Symbol
+	rest   Symbol
+	init   Expression
+}
+
+func (b valuesBinding) bind(env *Env, values Expression) error {
+	return bindFormals(env, b.params, b.rest, valuesToSlice(values))
+}
+
+func valuesBindings(name string, args []Expression) ([]valuesBinding, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("%s: syntax error (%s should pass the bindings and body)", name, name)
+	}
+	bindings, ok := args[0].([]Expression)
+	if !ok {
+		return nil, fmt.Errorf("%s: syntax error (not a valid binding)", name)
+	}
+	var ret []valuesBinding
+	for _, exp := range bindings {
+		binding, ok := exp.([]Expression)
+		if !ok || len(binding) != 2 {
+			return nil, fmt.Errorf("%s: syntax error (not a valid binding)", name)
+		}
+		params, rest, err := parseFormals(binding[0])
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, valuesBinding{params: params, rest: rest, init: binding[1]})
+	}
+	return ret, nil
+}
+
+// evalLetValues binds the values of the inits evaluated in env, (let-values (((q r) (floor/ 7 2))) body ...).
+func evalLetValues(m *machine, args []Expression, env *Env) error {
+	bindings, err := valuesBindings("let-values", args)
+	if err != nil {
+		return err
+	}
+	inits := make([]Expression, len(bindings))
+	for i, b := range bindings {
+		inits[i] = b.init
+	}
+	return m.evalArgs(inits, env, func(m *machine, values []Expression) error {
+		newEnv := &Env{outer: env, frame: make(map[Symbol]Expression)}
+		for i, b := range bindings {
+			if err := b.bind(newEnv, values[i]); err != nil {
+				return fmt.Errorf("let-values: %s", err)
+			}
+		}
+		m.evalBody(args[1:], newEnv)
+		return nil
+	})
+}
+
+// evalL2RLetValues binds the values in order, each init is evaluated in the scope of the previous bindings.
+func evalL2RLetValues(m *machine, args []Expression, env *Env) error {
+	bindings, err := valuesBindings("let*-values", args)
+	if err != nil {
+		return err
+	}
+	var bind func(m *machine, i int, currentEnv *Env) error
+	bind = func(m *machine, i int, currentEnv *Env) error {
+		if i == len(bindings) {
+			m.evalBody(args[1:], &Env{outer: currentEnv, frame: make(map[Symbol]Expression)})
+			return nil
+		}
+		m.evalThen(bindings[i].init, currentEnv, func(m *machine, values Expression) error {
+			newEnv := &Env{outer: currentEnv, frame: make(map[Symbol]Expression)}
+			if err := bindings[i].bind(newEnv, values); err != nil {
+				return fmt.Errorf("let*-values: %s", err)
+			}
+			return bind(m, i+1, newEnv)
+		})
+		return nil
+	}
+	return bind(m, 0, env)
+}
+
+// evalDefineValues defines the variables with the values, (define-values (q r) (floor/ 7 2)).
+func evalDefineValues(m *machine, args []Expression, env *Env) error {
+	if len(args) != 2 {
+		return errors.New("define-values: syntax error (requires formals and expression)")
+	}
+	params, rest, err := parseFormals(args[0])
+	if err != nil {
+		return err
+	}
+	m.evalThen(args[1], env, func(m *machine, values Expression) error {
+		if err := bindFormals(env, params, rest, valuesToSlice(values)); err != nil {
+			return fmt.Errorf("define-values: %s", err)
+		}
+		m.ret(UndefObj)
+		return nil
+	})
+	return nil
+}
+
+// evalReceive binds the values of the expression and evaluates the body, (receive (q r) (floor/ 7 2) body ...).
+func evalReceive(m *machine, args []Expression, env *Env) error {
+	if len(args) < 3 {
+		return errors.New("receive: syntax error (requires formals, expression and body)")
+	}
+	params, rest, err := parseFormals(args[0])
+	if err != nil {
+		return err
+	}
+	m.evalThen(args[1], env, func(m *machine, values Expression) error {
+		newEnv := &Env{outer: env, frame: make(map[Symbol]Expression)}
+		if err := bindFormals(newEnv, params, rest, valuesToSlice(values)); err != nil {
+			return fmt.Errorf("receive: %s", err)
+		}
+		m.evalBody(args[2:], newEnv)
+		return nil
+	})
+	return nil
+}
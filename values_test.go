@@ -0,0 +1,49 @@
+package goscheme
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValues(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"(values 1)", "1"},
+		{"(values 1 2)", "#<values 1 2>"},
+		{"(list (values 1 2) (values))", "(#<values 1 2> #<values>)"},
+		{"(call-with-values (lambda () (values 1 2)) +)", "3"},
+		{"(call-with-values (lambda () (values)) list)", "()"},
+		{"(call-with-values (lambda () 5) list)", "(5)"},
+		{"(let-values (((q r) (floor/ 7 2)) ((x) (values 'x))) (list q r x))", "(3 1 x)"},
+		{"(let-values (((a . rest) (values 1 2 3)) (all (values 4 5))) (list a rest all))", "(1 (2 3) (4 5))"},
+		{"(let ((a 1)) (let-values (((a) (values 2)) ((b) (values a))) (list a b)))", "(2 1)"},
+		{"(let*-values (((a b) (values 1 2)) ((c) (values (+ a b)))) (list a b c))", "(1 2 3)"},
+		{"(define-values (q r) (truncate/ -7 2)) (list q r)", "(-3 -1)"},
+		{"(define-values (first . others) (values 1 2 3)) (list first others)", "(1 (2 3))"},
+		{"(receive (root rest) (exact-integer-sqrt 17) (list root rest))", "(4 1)"},
+		{"(receive all (values 1 2) all)", "(1 2)"},
+		{"(+ 1 (call/cc (lambda (k) (call-with-values (lambda () (k 1)) list))))", "2"},
+		{"(call-with-values (lambda () (call/cc (lambda (k) (k 1 2)))) list)", "(1 2)"},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+
+	for _, input := range []string{"(let-values (((a b) (values 1))) a)", "(receive (a) (values 1 2) a)",
+		"(define-values (a b) 1)", "(let-values ((a)) 1)", "(receive (a) 1)", "(call-with-values (lambda () 1) 2)"} {
+		_, err := EvalAll(strToToken(input), env)
+		assert.NotNil(t, err, input)
+	}
+}
+
+func TestResultLines(t *testing.T) {
+	assert.Equal(t, []string{"#=>1\n", "#=>\"a\"\n"}, resultLines(MultipleValues{Integer(1), String("a")}))
+	assert.Equal(t, []string{"#=>#t\n"}, resultLines(true))
+	assert.Empty(t, resultLines(MultipleValues{}))
+	assert.Empty(t, resultLines(UndefObj))
+}
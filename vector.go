@@ -0,0 +1,210 @@
+package goscheme
+
+import (
+	"fmt"
+)
+
+// Vector is the fixed length container with constant time random access. Should only use with pointer.
+type Vector struct {
+	Elements []Expression
+}
+
+// String returns the string representing the *Vector such as #(1 2 3).
+func (v *Vector) String() string {
+	return writeDatum(v)
+}
+
+// IsVector checks whether the expression represents Vector.
+func IsVector(exp Expression) bool {
+	switch exp.(type) {
+	case *Vector, vectorLiteral:
+		return true
+	default:
+		return false
+	}
+}
+
+// vectorLiteral is the parsed #( ... ) syntax whose elements are not evaluated.
+type vectorLiteral []Expression
+
+func expressionToVector(exp Expression) (*Vector, error) {
+	v, ok := exp.(*Vector)
+	if !ok {
+		return nil, fmt.Errorf("%v is not a vector", valueToString(exp))
+	}
+	return v, nil
+}
+
+func isVectorFunc(args ...Expression) (Expression, error) {
+	_, ok := args[0].(*Vector)
+	return ok, nil
+}
+
+func makeVectorFunc(args ...Expression) (Expression, error) {
+	k, ok := args[0].(Integer)
+	if !ok || k < 0 {
+		return UndefObj, fmt.Errorf("make-vector: %v is not a valid length", valueToString(args[0]))
+	}
+	var fill Expression = UndefObj
+	if len(args) > 1 {
+		fill = args[1]
+	}
+	v := &Vector{Elements: make([]Expression, k)}
+	for i := range v.Elements {
+		v.Elements[i] = fill
+	}
+	return v, nil
+}
+
+func vectorFunc(args ...Expression) (Expression, error) {
+	return &Vector{Elements: append([]Expression{}, args...)}, nil
+}
+
+func vectorLengthFunc(args ...Expression) (Expression, error) {
+	v, err := expressionToVector(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	return Integer(len(v.Elements)), nil
+}
+
+func vectorRefFunc(args ...Expression) (Expression, error) {
+	v, err := expressionToVector(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	k, err := indexArg(args, 1, len(v.Elements))
+	if err != nil {
+		return UndefObj, fmt.Errorf("vector-ref: %s", err)
+	}
+	return v.Elements[k], nil
+}
+
+func vectorSetFunc(args ...Expression) (Expression, error) {
+	v, err := expressionToVector(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	k, err := indexArg(args, 1, len(v.Elements))
+	if err != nil {
+		return UndefObj, fmt.Errorf("vector-set!: %s", err)
+	}
+	v.Elements[k] = args[2]
+	return UndefObj, nil
+}
+
+// subvector returns the elements of the vector argument in the range specified by the optional arguments.
+func subvector(name string, args []Expression) ([]Expression, error) {
+	v, err := expressionToVector(args[0])
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := rangeArgs(args, 1, len(v.Elements))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	return v.Elements[start:end], nil
+}
+
+func vectorToListFunc(args ...Expression) (Expression, error) {
+	elements, err := subvector("vector->list", args)
+	if err != nil {
+		return UndefObj, err
+	}
+	return listImpl(elements...)
+}
+
+func listToVectorFunc(args ...Expression) (Expression, error) {
+	if !isList(args[0]) {
+		return UndefObj, fmt.Errorf("list->vector: %v is not a list", valueToString(args[0]))
+	}
+	return vectorFunc(extractList(args[0])...)
+}
+
+func vectorFillFunc(args ...Expression) (Expression, error) {
+	v, err := expressionToVector(args[0])
+	if err != nil {
+		return UndefObj, err
+	}
+	start, end, err := rangeArgs(args, 2, len(v.Elements))
+	if err != nil {
+		return UndefObj, fmt.Errorf("vector-fill!: %s", err)
+	}
+	for i := start; i < end; i++ {
+		v.Elements[i] = args[1]
+	}
+	return UndefObj, nil
+}
+
+func vectorCopyFunc(args ...Expression) (Expression, error) {
+	elements, err := subvector("vector-copy", args)
+	if err != nil {
+		return UndefObj, err
+	}
+	return vectorFunc(elements...)
+}
+
+func vectorAppendFunc(args ...Expression) (Expression, error) {
+	ret := &Vector{}
+	for _, arg := range args {
+		v, err := expressionToVector(arg)
+		if err != nil {
+			return UndefObj, err
+		}
+		ret.Elements = append(ret.Elements, v.Elements...)
+	}
+	return ret, nil
+}
+
+// walkVectors calls the procedure args[0] with the elements at each index of the vectors args[1:] up to the length of
+// the shortest one, and passes the values of the calls to then when collect is true.
+func walkVectors(m *machine, name string, args []Expression, collect bool, then func(m *machine, values []Expression) error) error {
+	vectors := make([]*Vector, len(args)-1)
+	n := -1
+	for i, arg := range args[1:] {
+		v, err := expressionToVector(arg)
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		vectors[i] = v
+		if n < 0 || len(v.Elements) < n {
+			n = len(v.Elements)
+		}
+	}
+	var next func(m *machine, i int, values []Expression) error
+	next = func(m *machine, i int, values []Expression) error {
+		if i == n {
+			return then(m, values)
+		}
+		m.push(func(m *machine, value Expression) error {
+			if !collect {
+				return next(m, i+1, values)
+			}
+			// copy the values, the frame may be resumed again by a continuation
+			return next(m, i+1, append(values[:i:i], value))
+		})
+		elements := make([]Expression, len(vectors))
+		for j, v := range vectors {
+			elements[j] = v.Elements[i]
+		}
+		return m.apply(args[0], elements)
+	}
+	return next(m, 0, []Expression{})
+}
+
+// vectorMap returns the vector of the values of the procedure applied to the elements of the vectors elementwise.
+// (vector-map + #(1 2) #(10 20 30)) => #(11 22)
+func vectorMap(m *machine, args ...Expression) error {
+	return walkVectors(m, "vector-map", args, true, func(m *machine, values []Expression) error {
+		m.ret(&Vector{Elements: values})
+		return nil
+	})
+}
+
+// vectorForEach calls the procedure with the elements of the vectors elementwise for the side effects.
+func vectorForEach(m *machine, args ...Expression) error {
+	return walkVectors(m, "vector-for-each", args, false, func(m *machine, _ []Expression) error {
+		m.ret(UndefObj)
+		return nil
+	})
+}
@@ -0,0 +1,75 @@
+package goscheme
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestVector_String(t *testing.T) {
+	testCases := []struct {
+		input    *Vector
+		expected string
+	}{
+		{&Vector{}, "#()"},
+		{&Vector{Elements: []Expression{Integer(1), String("a"), Symbol("b")}}, `#(1 "a" b)`},
+		{&Vector{Elements: []Expression{true, &Vector{Elements: []Expression{Char('c')}}}}, `#(#t #(#\c))`},
+	}
+	for _, c := range testCases {
+		assert.Equal(t, c.expected, c.input.String())
+	}
+}
+
+func TestVectorFunctions(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"#(1 2 3)", "#(1 2 3)"},
+		{"#()", "#()"},
+		{`#(a "b" #\c (1 2) #(3))`, `#(a "b" #\c (1 2) #(3))`},
+		{"'#(1 x)", "#(1 x)"},
+		{"'(1 #(x))", "(1 #(x))"},
+		{"(vector? #(1))", "#t"},
+		{"(vector? '(1))", "#f"},
+		{"(vector 1 (+ 1 1))", "#(1 2)"},
+		{"(make-vector 2 'a)", "#(a a)"},
+		{"(vector-length (make-vector 3))", "3"},
+		{"(vector-ref #(1 2 3) 1)", "2"},
+		{"(let ((v (vector 1 2 3))) (vector-set! v 0 'x) v)", "#(x 2 3)"},
+		{"(vector->list #(1 2 3))", "(1 2 3)"},
+		{"(vector->list #(1 2 3) 1)", "(2 3)"},
+		{"(vector->list #(1 2 3) 1 2)", "(2)"},
+		{"(list->vector '(1 (2)))", "#(1 (2))"},
+		{"(let ((v (vector 1 2 3))) (vector-fill! v 0) v)", "#(0 0 0)"},
+		{"(let ((v (vector 1 2 3))) (vector-fill! v 0 1 2) v)", "#(1 0 3)"},
+		{"(vector-copy #(1 2 3))", "#(1 2 3)"},
+		{"(vector-copy #(1 2 3) 1)", "#(2 3)"},
+		{"(let* ((a (vector 1 2)) (b (vector-copy a))) (vector-set! b 0 'x) a)", "#(1 2)"},
+		{"(vector-append #(1) #(2 3))", "#(1 2 3)"},
+		{"(vector-map (lambda (x) (* x x)) #(1 2 3))", "#(1 4 9)"},
+		{"(let ((sum 0)) (vector-for-each (lambda (x) (set! sum (+ sum x))) #(1 2 3)) sum)", "6"},
+		{"(vector-map + #(1 2) #(10 20 30))", "#(11 22)"},
+		{"(vector-map (lambda (x) x) #())", "#()"},
+		{"(define (f) #(1 2)) (vector-set! (f) 0 9) (f)", "#(1 2)"},
+		{"(define (g) '#(1 2)) (vector-set! (g) 0 9) (g)", "#(1 2)"},
+		{"(let ((acc '())) (vector-for-each (lambda (a b c) (set! acc (cons (list a b c) acc))) #(1 2) #(x y) #(#t #f)) acc)",
+			"((2 y #f) (1 x #t))"},
+		{"(define k #f) (define r '())" +
+			"(let ((v (vector-map (lambda (x) (call/cc (lambda (c) (if (= x 2) (set! k c)) x))) #(1 2 3))))" +
+			" (set! r (cons v r)) (if (< (list-length r) 2) (k 20) r))", "(#(1 20 3) #(1 2 3))"},
+		{"(eval '#(1 2))", "#(1 2)"},
+	}
+	env := setupBuiltinEnv()
+	for _, c := range testCases {
+		ret, err := EvalAll(strToToken(c.input), env)
+		assert.Nil(t, err, c.input)
+		assert.Equal(t, c.expected, valueToString(ret), c.input)
+	}
+
+	for _, input := range []string{"(vector-ref #(1 2) 2)", "(vector-ref '(1 2) 0)", "(vector-set! #(1) -1 0)",
+		"(make-vector -1)", "(vector-copy #(1 2) 2 1)", "(list->vector 1)", "(vector-fill! #(1) 0 2)",
+		"(vector-map car #(1) (list 1))", "(vector-for-each car)"} {
+		_, err := EvalAll(strToToken(input), env)
+		assert.NotNil(t, err, input)
+	}
+}